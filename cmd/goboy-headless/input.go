@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/pad"
+)
+
+var buttons = map[string]pad.Button{
+	"a":      pad.A,
+	"b":      pad.B,
+	"select": pad.Select,
+	"start":  pad.Start,
+	"right":  pad.Right,
+	"left":   pad.Left,
+	"up":     pad.Up,
+	"down":   pad.Down,
+}
+
+type inputEvent struct {
+	frame   int
+	buttons pad.Button
+}
+
+// Script holds buttons from the frame until the next event
+type Script struct {
+	events []inputEvent
+}
+
+// ParseScript reads input script, each line is
+//
+//	<frame> [button...]
+//
+// buttons are held from the frame, and no button releases all
+// e.g.
+//
+//	# press start for 5 frames
+//	120 start
+//	125
+func ParseScript(r io.Reader) (*Script, error) {
+	s := &Script{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if i := strings.IndexByte(text, '#'); i >= 0 {
+			text = text[:i]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+
+		frame, err := strconv.Atoi(fields[0])
+		if err != nil || frame < 0 {
+			return nil, fmt.Errorf("line %d: invalid frame %q", line, fields[0])
+		}
+		var held pad.Button
+		for _, name := range fields[1:] {
+			b, ok := buttons[strings.ToLower(name)]
+			if !ok {
+				return nil, fmt.Errorf("line %d: unknown button %q", line, name)
+			}
+			held |= b
+		}
+		s.events = append(s.events, inputEvent{frame, held})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(s.events, func(i, j int) bool {
+		return s.events[i].frame < s.events[j].frame
+	})
+	return s, nil
+}
+
+// Buttons returns buttons held at the frame
+func (s *Script) Buttons(frame int) pad.Button {
+	var held pad.Button
+	for _, e := range s.events {
+		if e.frame > frame {
+			break
+		}
+		held = e.buttons
+	}
+	return held
+}
+
+// scriptInput is gb.InputProvider playing the script from frame 0
+type scriptInput struct {
+	script *Script
+	frame  int
+}
+
+func (s *scriptInput) Buttons() pad.Button {
+	buttons := s.script.Buttons(s.frame)
+	s.frame++
+	return buttons
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/pad"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseScript(t *testing.T) {
+	script := `
+# press start
+120 start
+125
+130 A right # comment
+140 up
+`
+	s, err := ParseScript(strings.NewReader(script))
+	assert.NoError(t, err)
+
+	tests := []struct {
+		frame int
+		want  pad.Button
+	}{
+		{0, 0},
+		{120, pad.Start},
+		{124, pad.Start},
+		{125, 0},
+		{130, pad.A | pad.Right},
+		{200, pad.Up},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, s.Buttons(tt.frame), "frame %d", tt.frame)
+	}
+
+	_, err = ParseScript(strings.NewReader("10 turbo"))
+	assert.Error(t, err)
+	_, err = ParseScript(strings.NewReader("start"))
+	assert.Error(t, err)
+}
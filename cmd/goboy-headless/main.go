@@ -0,0 +1,197 @@
+// goboy-headless runs a ROM without window, for CI and test ROMs
+//
+//	goboy-headless [flags] rom.gb
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/cpu"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/gpu"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/serial"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/movie"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/types"
+)
+
+var (
+	frames     = flag.Int("frames", 600, "maximum number of frames to run")
+	until      = flag.String("until", "", "stop when serial output contains this string")
+	input      = flag.String("input", "", "input script file")
+	screenshot = flag.String("screenshot", "", "write the last frame to this PNG file")
+	serialOut  = flag.String("serial", "", "write serial output to this file, - for stdout")
+	dump       = flag.String("dump", "", "write registers and memory to this file, - for stdout")
+	record     = flag.String("record", "", "record input to this movie file")
+	play       = flag.String("play", "", "play movie file instead of input script, for frames recorded in it")
+	verify     = flag.Bool("verify", false, "fail when the last frame differs from the movie played")
+	hash       = flag.Bool("hash", false, "print hash of the last frame")
+	fifo       = flag.Bool("fifo", false, "draw lines with pixel FIFO renderer")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] rom.gb\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	romData, err := ioutil.ReadFile(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	script := &Script{}
+	if *input != "" {
+		f, err := os.Open(*input)
+		if err != nil {
+			log.Fatal(err)
+		}
+		script, err = ParseScript(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("%s: %v", *input, err)
+		}
+	}
+
+	g := gb.NewGB(romData)
+	g.SetInputProvider(&scriptInput{script: script})
+	if *fifo {
+		g.SetRenderer(gpu.FIFORenderer)
+	}
+	maxFrames := *frames
+
+	var player *movie.Player
+	if *play != "" {
+		m, err := readMovie(*play)
+		if err != nil {
+			log.Fatalf("%s: %v", *play, err)
+		}
+		player, err = movie.NewPlayer(g, romData, m)
+		if err != nil {
+			log.Fatalf("%s: %v", *play, err)
+		}
+		maxFrames = int(m.Frames)
+	}
+
+	var recorder *movie.Recorder
+	if *record != "" {
+		var input gb.InputProvider = &scriptInput{script: script}
+		if player != nil {
+			input = player
+		}
+		recorder, err = movie.NewRecorder(g, romData, input)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var sink serial.Sink
+	g.SetSerialOutput(sink.Write)
+
+	found := false
+	for frame := 0; frame < maxFrames; frame++ {
+		g.Step()
+
+		if *until != "" && sink.Contains(*until) {
+			found = true
+			break
+		}
+	}
+
+	if recorder != nil {
+		if err := writeTo(*record, recorder.Finish(g).Write); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *serialOut != "" {
+		if err := writeTo(*serialOut, func(w io.Writer) error {
+			_, err := w.Write(sink.Bytes())
+			return err
+		}); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *screenshot != "" {
+		if err := writeTo(*screenshot, func(w io.Writer) error {
+			img, _ := g.Display()
+			return png.Encode(w, img)
+		}); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *hash {
+		fmt.Printf("%x\n", g.FrameHash())
+	}
+	if *dump != "" {
+		if err := writeTo(*dump, func(w io.Writer) error {
+			return dumpState(w, g)
+		}); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *until != "" && !found {
+		fmt.Fprintf(os.Stderr, "%q was not found in serial output in %d frames\n", *until, maxFrames)
+		os.Exit(1)
+	}
+	if *verify && player != nil {
+		if err := player.Verify(g); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", *play, err)
+			os.Exit(1)
+		}
+	}
+}
+
+func readMovie(path string) (*movie.Movie, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return movie.Read(f)
+}
+
+func writeTo(path string, write func(io.Writer) error) error {
+	if path == "-" {
+		return write(os.Stdout)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// dumpState writes registers and memory from 0x8000, ROM is not dumped
+func dumpState(w io.Writer, g *gb.GB) error {
+	r := g.Registers()
+	fmt.Fprintf(w, "AF:%04X BC:%04X DE:%04X HL:%04X SP:%04X PC:%04X\n", r.AF(), r.BC(), r.DE(), r.HL(), r.SP, r.PC)
+	fmt.Fprintf(w, "A:%02X F:%02X B:%02X C:%02X D:%02X E:%02X H:%02X L:%02X\n",
+		r.R[cpu.A], r.R[cpu.F], r.R[cpu.B], r.R[cpu.C], r.R[cpu.D], r.R[cpu.E], r.R[cpu.H], r.R[cpu.L])
+
+	for addr := 0x8000; addr <= 0xFFFF; addr += 16 {
+		fmt.Fprintf(w, "%04X:", addr)
+		for i := 0; i < 16; i++ {
+			fmt.Fprintf(w, " %02X", g.ReadByte(types.Addr(addr+i)))
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
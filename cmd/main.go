@@ -6,20 +6,43 @@ import (
 	"os"
 
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/emulator"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/cartridge"
 	"github.com/hajimehoshi/ebiten/v2"
 )
 
 func main() {
+	os.Exit(run())
+}
+
+// run returns exit code, so that deferred Flush is called before exit
+func run() (code int) {
 	romData, err := ioutil.ReadFile(os.Args[1])
 
 	if err != nil {
-		log.Fatal(err)
-		os.Exit(-1)
+		log.Print(err)
+		return 1
 	}
 
 	e := emulator.New(romData)
 
+	storage := cartridge.NewFileStorage(cartridge.SavPath(os.Args[1]))
+	if err := e.GB.Cartridge.SetStorage(storage); err != nil {
+		log.Print(err)
+		return 1
+	}
+
+	// battery RAM is saved even when the game ends by error
+	defer func() {
+		if err := e.GB.Cartridge.Flush(); err != nil {
+			log.Print(err)
+			code = 1
+		}
+	}()
+
 	if err := ebiten.RunGame(e); err != nil {
-		log.Fatal(err)
+		log.Print(err)
+		return 1
 	}
+
+	return 0
 }
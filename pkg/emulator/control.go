@@ -0,0 +1,83 @@
+package emulator
+
+import (
+	"fmt"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+type Action int
+
+const (
+	Pause Action = iota
+	FrameAdvance
+	SpeedUp
+	SpeedDown
+	// toggles running as fast as possible
+	Uncapped
+	// rewinds while held
+	Rewind
+)
+
+var DefaultHotkeys = map[Action]ebiten.Key{
+	Pause:        ebiten.KeyP,
+	FrameAdvance: ebiten.KeyN,
+	SpeedUp:      ebiten.KeyEqual,
+	SpeedDown:    ebiten.KeyMinus,
+	Uncapped:     ebiten.KeyTab,
+	Rewind:       ebiten.KeyR,
+}
+
+// Speeds are multipliers selected by SpeedUp and SpeedDown
+var Speeds = []float64{0.25, 0.5, 1, 2, 4, 8}
+
+const normalSpeed = 2
+
+func (e *Emulator) handleHotkeys() {
+	for action, key := range e.Hotkeys {
+		if action != Rewind && inpututil.IsKeyJustPressed(key) {
+			e.Do(action)
+		}
+	}
+}
+
+// Do runs the action as if the hotkey is pressed
+func (e *Emulator) Do(action Action) {
+	s := e.Scheduler
+	switch action {
+	case Pause:
+		s.SetPaused(!s.Paused())
+	case FrameAdvance:
+		// pauses at first
+		if !s.Paused() {
+			s.SetPaused(true)
+			return
+		}
+		s.Advance()
+	case SpeedUp:
+		if e.speed < len(Speeds)-1 {
+			e.speed++
+			s.SetSpeed(Speeds[e.speed])
+		}
+	case SpeedDown:
+		if e.speed > 0 {
+			e.speed--
+			s.SetSpeed(Speeds[e.speed])
+		}
+	case Uncapped:
+		s.SetUnthrottled(!s.Unthrottled())
+	}
+}
+
+func (e *Emulator) title() string {
+	s := e.Scheduler
+	switch {
+	case s.Paused():
+		return "GoBoy paused"
+	case s.Unthrottled():
+		return fmt.Sprintf("GoBoy uncapped %.0f%%", s.Measured())
+	default:
+		return fmt.Sprintf("GoBoy %gx %.0f%%", s.Speed(), s.Measured())
+	}
+}
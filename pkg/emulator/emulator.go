@@ -1,28 +1,55 @@
 package emulator
 
 import (
-	"fmt"
 	"image"
 	"math"
+	"time"
 
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/emulator/rewind"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/emulator/scheduler"
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb"
 	"github.com/hajimehoshi/ebiten/v2"
 )
 
+// save RAM is flushed every 5 seconds
+const flushInterval = 60 * 5
+
+// 10 seconds can be rewound
+const (
+	rewindInterval = 5
+	rewindDepth    = 60 * 10 / rewindInterval
+	rewindMaxBytes = 32 << 20
+)
+
 type Emulator struct {
-	RomData []byte
-	GB      *gb.GB
+	RomData   []byte
+	GB        *gb.GB
+	Scheduler *scheduler.Scheduler
+	Rewind    *rewind.Buffer
+	// keys to control emulation, which can be replaced before running
+	Hotkeys map[Action]ebiten.Key
+	frames  uint
+	// index of Speeds
+	speed int
 }
 
 func New(romData []byte) *Emulator {
 	ebiten.SetWindowSize(640, 480)
-	ebiten.SetWindowTitle("60fps")
+	ebiten.SetWindowTitle("GoBoy")
 	ebiten.SetWindowSize(160*4, 144*4)
 
 	gb := gb.NewGB(romData)
+	gb.SetInputProvider(NewKeyboard())
 	e := &Emulator{
-		RomData: romData,
-		GB:      gb,
+		RomData:   romData,
+		GB:        gb,
+		Scheduler: scheduler.New(time.Now),
+		Rewind:    rewind.New(rewindDepth, rewindMaxBytes, rewindInterval),
+		Hotkeys:   map[Action]ebiten.Key{},
+		speed:     normalSpeed,
+	}
+	for action, key := range DefaultHotkeys {
+		e.Hotkeys[action] = key
 	}
 
 	return e
@@ -33,8 +60,36 @@ func (e *Emulator) Layout(outsideWidth, outsideHeight int) (screenWidth, screenH
 }
 
 func (e *Emulator) Update() error {
-	e.GB.Step()
-	ebiten.SetWindowTitle(fmt.Sprintf("%f", ebiten.CurrentTPS()))
+	e.handleHotkeys()
+
+	// a snapshot is restored every tick while the key is held
+	if key, ok := e.Hotkeys[Rewind]; ok && ebiten.IsKeyPressed(key) {
+		if _, err := e.Rewind.Rewind(e.GB); err != nil {
+			return err
+		}
+		e.Scheduler.Reset()
+		ebiten.SetWindowTitle("GoBoy rewinding")
+		return nil
+	}
+
+	var err error
+	e.Scheduler.Run(func() {
+		if err != nil {
+			return
+		}
+		e.GB.Step()
+		e.frames++
+		if e.frames%flushInterval == 0 {
+			err = e.GB.Cartridge.Flush()
+		}
+		if err == nil {
+			err = e.Rewind.Capture(e.GB)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	ebiten.SetWindowTitle(e.title())
 	return nil
 }
 
@@ -2,33 +2,35 @@ package emulator
 
 import (
 	"io/ioutil"
-	"log"
-	"os"
 	"testing"
 
-	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/serial"
 	"github.com/stretchr/testify/assert"
 )
 
-func testrom(t assert.TestingT, file string, passstr string) {
+func testrom(t *testing.T, file string, passstr string, frames int) {
 	romData, err := ioutil.ReadFile(file)
-
 	if err != nil {
-		log.Fatal(err)
-		os.Exit(-1)
+		t.Fatal(err)
 	}
 
 	e := New(romData)
-
-	if err := ebiten.RunGame(e); err != nil {
-		log.Fatal(err)
+	var sink serial.Sink
+	e.GB.SetSerialOutput(sink.Write)
+
+	for i := 0; i < frames; i++ {
+		e.GB.Step()
+		if sink.Contains(passstr) || sink.Contains("Failed") {
+			break
+		}
 	}
+
+	assert.Contains(t, sink.String(), passstr)
 }
 
 func TestCPU_Blargg_cpu_instrs(t *testing.T) {
-	file := "../../test/blargg/cpu_instrs/cpu_instrs.gb"
+	file := "../../test/rom/blargg/cpu_instrs/cpu_instrs.gb"
 	passstr := "cpu_instrs\n\n01:ok  02:ok  03:ok  04:ok  05:ok  06:ok  07:ok  08:ok  09:ok  10:ok  11:ok  \n\nPassed all tests\n"
 
-	testrom(t, file, passstr)
-
+	testrom(t, file, passstr, 3200)
 }
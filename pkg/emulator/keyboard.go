@@ -0,0 +1,38 @@
+package emulator
+
+import (
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/pad"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+var DefaultKeyMap = map[ebiten.Key]pad.Button{
+	ebiten.KeyZ:         pad.A,
+	ebiten.KeyX:         pad.B,
+	ebiten.KeyBackspace: pad.Select,
+	ebiten.KeyEnter:     pad.Start,
+	ebiten.KeyRight:     pad.Right,
+	ebiten.KeyLeft:      pad.Left,
+	ebiten.KeyUp:        pad.Up,
+	ebiten.KeyDown:      pad.Down,
+}
+
+// Keyboard is gb.InputProvider reading keys held on ebiten window
+type Keyboard struct {
+	KeyMap map[ebiten.Key]pad.Button
+}
+
+func NewKeyboard() *Keyboard {
+	return &Keyboard{
+		KeyMap: DefaultKeyMap,
+	}
+}
+
+func (k *Keyboard) Buttons() pad.Button {
+	var buttons pad.Button
+	for key, button := range k.KeyMap {
+		if ebiten.IsKeyPressed(key) {
+			buttons |= button
+		}
+	}
+	return buttons
+}
@@ -0,0 +1,177 @@
+package rewind
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+	"io/ioutil"
+)
+
+// Machine is implemented by gb.GB
+type Machine interface {
+	SaveState(w io.Writer) error
+	LoadState(r io.Reader) error
+}
+
+// older snapshot is kept as XOR with the next newer one,
+// which is mostly zero and compressed well
+type delta struct {
+	data []byte
+	// size of the snapshot, which can be different from the newer one
+	size int
+}
+
+// Buffer keeps snapshots taken every Interval frames
+// The newest snapshot is kept as is, and older ones as compressed delta
+type Buffer struct {
+	Interval int
+
+	// ring buffer of deltas, oldest is at start
+	deltas []delta
+	start  int
+	count  int
+	// total bytes of deltas
+	size     int
+	maxBytes int
+
+	latest []byte
+	frames int
+}
+
+// New creates buffer which holds depth snapshots at most,
+// and drops old snapshots when deltas exceed maxBytes
+func New(depth, maxBytes, interval int) *Buffer {
+	if depth < 1 {
+		depth = 1
+	}
+	if interval < 1 {
+		interval = 1
+	}
+	return &Buffer{
+		Interval: interval,
+		deltas:   make([]delta, depth-1),
+		maxBytes: maxBytes,
+	}
+}
+
+// Len returns the number of snapshots
+func (b *Buffer) Len() int {
+	if b.latest == nil {
+		return 0
+	}
+	return b.count + 1
+}
+
+// Size returns bytes used by snapshots
+func (b *Buffer) Size() int {
+	return b.size + len(b.latest)
+}
+
+// Capture is called every frame, and takes snapshot every Interval frames
+func (b *Buffer) Capture(m Machine) error {
+	b.frames++
+	if b.frames < b.Interval {
+		return nil
+	}
+	b.frames = 0
+
+	var buf bytes.Buffer
+	if err := m.SaveState(&buf); err != nil {
+		return err
+	}
+	b.push(buf.Bytes())
+	return nil
+}
+
+func (b *Buffer) push(snapshot []byte) {
+	if b.latest != nil && len(b.deltas) > 0 {
+		if b.count == len(b.deltas) {
+			b.dropOldest()
+		}
+		d := encode(b.latest, snapshot)
+		b.deltas[(b.start+b.count)%len(b.deltas)] = d
+		b.count++
+		b.size += len(d.data)
+		for b.size > b.maxBytes && b.count > 0 {
+			b.dropOldest()
+		}
+	}
+	b.latest = snapshot
+}
+
+func (b *Buffer) dropOldest() {
+	b.size -= len(b.deltas[b.start].data)
+	b.deltas[b.start] = delta{}
+	b.start = (b.start + 1) % len(b.deltas)
+	b.count--
+}
+
+// Rewind restores the newest snapshot and removes it from buffer
+// It returns false when there is no snapshot
+func (b *Buffer) Rewind(m Machine) (bool, error) {
+	if b.latest == nil {
+		return false, nil
+	}
+
+	if err := m.LoadState(bytes.NewReader(b.latest)); err != nil {
+		return false, err
+	}
+	b.frames = 0
+
+	if b.count == 0 {
+		b.latest = nil
+		return true, nil
+	}
+	i := (b.start + b.count - 1) % len(b.deltas)
+	d := b.deltas[i]
+	b.deltas[i] = delta{}
+	b.count--
+	b.size -= len(d.data)
+
+	older, err := decode(d, b.latest)
+	if err != nil {
+		return true, err
+	}
+	b.latest = older
+	return true, nil
+}
+
+// Clear removes all snapshots, e.g. after loading other state
+func (b *Buffer) Clear() {
+	for b.count > 0 {
+		b.dropOldest()
+	}
+	b.start = 0
+	b.latest = nil
+	b.frames = 0
+}
+
+func encode(older, newer []byte) delta {
+	x := make([]byte, len(older))
+	copy(x, older)
+	for i := 0; i < len(x) && i < len(newer); i++ {
+		x[i] ^= newer[i]
+	}
+
+	var buf bytes.Buffer
+	// error never happens with valid level
+	w, _ := flate.NewWriter(&buf, flate.BestSpeed)
+	w.Write(x)
+	w.Close()
+	return delta{data: buf.Bytes(), size: len(older)}
+}
+
+func decode(d delta, newer []byte) ([]byte, error) {
+	x, err := ioutil.ReadAll(flate.NewReader(bytes.NewReader(d.data)))
+	if err != nil {
+		return nil, err
+	}
+	if len(x) != d.size {
+		return nil, errors.New("rewind: broken snapshot")
+	}
+	for i := 0; i < len(x) && i < len(newer); i++ {
+		x[i] ^= newer[i]
+	}
+	return x, nil
+}
@@ -0,0 +1,137 @@
+package rewind
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/pad"
+	"github.com/Teshima-Tatsuya/GoBoy/test/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeMachine has a counter as its state
+type fakeMachine struct {
+	state []byte
+}
+
+func (m *fakeMachine) SaveState(w io.Writer) error {
+	_, err := w.Write(m.state)
+	return err
+}
+
+func (m *fakeMachine) LoadState(r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	m.state = data
+	return err
+}
+
+func (m *fakeMachine) step() {
+	state := make([]byte, 1024)
+	copy(state, m.state)
+	state[0]++
+	m.state = state
+}
+
+func TestBuffer_Rewind(t *testing.T) {
+	t.Run("reverse order", func(t *testing.T) {
+		m := &fakeMachine{}
+		b := New(10, 1<<20, 2)
+		for i := 0; i < 10; i++ {
+			m.step()
+			assert.NoError(t, b.Capture(m))
+		}
+		assert.Equal(t, 5, b.Len())
+
+		for _, want := range []byte{10, 8, 6, 4, 2} {
+			ok, err := b.Rewind(m)
+			assert.True(t, ok)
+			assert.NoError(t, err)
+			assert.Equal(t, want, m.state[0])
+		}
+		ok, err := b.Rewind(m)
+		assert.False(t, ok)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, b.Len())
+	})
+
+	t.Run("depth", func(t *testing.T) {
+		m := &fakeMachine{}
+		b := New(3, 1<<20, 1)
+		for i := 0; i < 10; i++ {
+			m.step()
+			b.Capture(m)
+		}
+		assert.Equal(t, 3, b.Len())
+
+		for _, want := range []byte{10, 9, 8} {
+			b.Rewind(m)
+			assert.Equal(t, want, m.state[0])
+		}
+		ok, _ := b.Rewind(m)
+		assert.False(t, ok)
+	})
+
+	t.Run("memory cap", func(t *testing.T) {
+		m := &fakeMachine{}
+		b := New(100, 0, 1)
+		for i := 0; i < 10; i++ {
+			m.step()
+			b.Capture(m)
+		}
+		// only the newest snapshot is kept
+		assert.Equal(t, 1, b.Len())
+		assert.Equal(t, 1024, b.Size())
+	})
+
+	t.Run("deltas are compressed", func(t *testing.T) {
+		m := &fakeMachine{}
+		b := New(100, 1<<20, 1)
+		for i := 0; i < 10; i++ {
+			m.step()
+			b.Capture(m)
+		}
+		assert.Less(t, b.Size(), 1024*2)
+	})
+}
+
+func TestBuffer_GB(t *testing.T) {
+	romData, err := ioutil.ReadFile("../../../test/rom/helloworld/hello.gb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := gb.NewGB(romData)
+	g.SetInputProvider(mock.NewMockInput(pad.Start))
+	b := New(10, 1<<20, 5)
+
+	var want []byte
+	for i := 1; i <= 30; i++ {
+		g.Step()
+		assert.NoError(t, b.Capture(g))
+		if i == 30 {
+			var buf bytes.Buffer
+			g.SaveState(&buf)
+			want = buf.Bytes()
+		}
+	}
+	wantScreen, _ := g.Display()
+	wantPix := append([]byte(nil), wantScreen.Pix...)
+
+	// back to frame 20
+	for i := 0; i < 3; i++ {
+		ok, err := b.Rewind(g)
+		assert.True(t, ok)
+		assert.NoError(t, err)
+	}
+	for i := 0; i < 10; i++ {
+		g.Step()
+	}
+
+	var got bytes.Buffer
+	g.SaveState(&got)
+	assert.Equal(t, want, got.Bytes())
+	screen, _ := g.Display()
+	assert.Equal(t, wantPix, screen.Pix)
+}
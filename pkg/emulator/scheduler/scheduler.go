@@ -0,0 +1,158 @@
+package scheduler
+
+import (
+	"math"
+	"time"
+)
+
+// @see https://gbdev.io/pandocs/Rendering.html#frame-timing
+const (
+	ClockSpeed  = 4194304
+	FrameCycles = 70224
+	// about 59.73 Hz
+	FramesPerSecond = float64(ClockSpeed) / FrameCycles
+	FrameDuration   = time.Second * FrameCycles / ClockSpeed
+)
+
+const (
+	// frames are dropped instead of catching up when emulation is behind more than this
+	maxCatchUp = 4
+	// wall time spent on one Run in unthrottled mode, which is a tick of 60 TPS
+	unthrottledBudget = time.Second / 60
+	// actual speed is measured in this interval
+	measureInterval = time.Second
+)
+
+// Scheduler keeps emulated time synced to wall clock
+// now is a time source, which can be replaced for testing
+type Scheduler struct {
+	now func() time.Time
+
+	// frames run from base
+	base   time.Time
+	frames int64
+
+	// multiplier of emulated speed
+	speed       float64
+	unthrottled bool
+	paused      bool
+	// frames requested to run while paused
+	advance int
+
+	measureStart  time.Time
+	measureFrames int
+	measured      float64
+}
+
+func New(now func() time.Time) *Scheduler {
+	return &Scheduler{
+		now:          now,
+		base:         now(),
+		speed:        1,
+		measureStart: now(),
+		measured:     100,
+	}
+}
+
+// Reset syncs emulated time to now, call after emulation is stopped for a while
+func (s *Scheduler) Reset() {
+	s.base = s.now()
+	s.frames = 0
+}
+
+// SetUnthrottled runs frames as fast as possible when true
+func (s *Scheduler) SetUnthrottled(unthrottled bool) {
+	if s.unthrottled != unthrottled {
+		s.unthrottled = unthrottled
+		s.Reset()
+	}
+}
+
+func (s *Scheduler) Unthrottled() bool {
+	return s.unthrottled
+}
+
+// SetSpeed sets multiplier of emulated speed, e.g. 2 for fast-forward and 0.5 for slow-motion
+func (s *Scheduler) SetSpeed(speed float64) {
+	if speed <= 0 {
+		return
+	}
+	s.speed = speed
+	s.Reset()
+}
+
+func (s *Scheduler) Speed() float64 {
+	return s.speed
+}
+
+func (s *Scheduler) SetPaused(paused bool) {
+	if s.paused != paused {
+		s.paused = paused
+		s.advance = 0
+		s.Reset()
+	}
+}
+
+func (s *Scheduler) Paused() bool {
+	return s.paused
+}
+
+// Advance runs a single frame on next Run while paused
+func (s *Scheduler) Advance() {
+	if s.paused {
+		s.advance++
+	}
+}
+
+// Run calls step for each frame to be run now, and returns the number of frames run
+func (s *Scheduler) Run(step func()) int {
+	var n int
+	if s.paused {
+		for ; s.advance > 0; s.advance-- {
+			step()
+			n++
+		}
+		s.Reset()
+	} else if s.unthrottled {
+		start := s.now()
+		for n == 0 || s.now().Sub(start) < unthrottledBudget {
+			step()
+			n++
+		}
+		s.base = s.now()
+		s.frames = 0
+	} else {
+		target := int64(float64(s.now().Sub(s.base)) * s.speed / float64(FrameDuration))
+		pending := target - s.frames
+		if max := int64(math.Ceil(maxCatchUp * s.speed)); pending > max {
+			pending = max
+			s.frames = target - max
+		}
+		for i := int64(0); i < pending; i++ {
+			step()
+			n++
+		}
+		s.frames += pending
+	}
+
+	s.measure(n)
+	return n
+}
+
+func (s *Scheduler) measure(frames int) {
+	s.measureFrames += frames
+	elapsed := s.now().Sub(s.measureStart)
+	if elapsed < measureInterval {
+		return
+	}
+
+	emulated := time.Duration(s.measureFrames) * FrameDuration
+	s.measured = float64(emulated) / float64(elapsed) * 100
+	s.measureStart = s.now()
+	s.measureFrames = 0
+}
+
+// Measured returns actual emulated speed in percentage of real hardware
+func (s *Scheduler) Measured() float64 {
+	return s.measured
+}
@@ -0,0 +1,145 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Teshima-Tatsuya/GoBoy/test/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func setup() (*Scheduler, *mock.MockClock) {
+	clock := mock.NewMockClock(time.Unix(0, 0))
+	return New(clock.Now), clock
+}
+
+func TestScheduler_Run(t *testing.T) {
+	t.Run("synced to wall clock", func(t *testing.T) {
+		s, clock := setup()
+		step := func() {}
+
+		total := 0
+		// 60 ticks of ebiten
+		for i := 0; i < 60; i++ {
+			clock.Advance(time.Second / 60)
+			total += s.Run(step)
+		}
+		assert.Equal(t, 59, total)
+
+		total = 0
+		for i := 0; i < 60*60; i++ {
+			clock.Advance(time.Second / 60)
+			total += s.Run(step)
+		}
+		// 59.73 Hz
+		assert.Equal(t, 3584, total)
+	})
+
+	t.Run("drop frames when behind", func(t *testing.T) {
+		s, clock := setup()
+		clock.Advance(time.Second)
+		assert.Equal(t, maxCatchUp, s.Run(func() {}))
+		assert.Equal(t, 0, s.Run(func() {}))
+
+		clock.Advance(FrameDuration)
+		assert.Equal(t, 1, s.Run(func() {}))
+	})
+
+	t.Run("unthrottled", func(t *testing.T) {
+		s, clock := setup()
+		s.SetUnthrottled(true)
+		step := func() {
+			clock.Advance(time.Millisecond)
+		}
+		assert.Equal(t, 17, s.Run(step))
+
+		// at least 1 frame even if it is slower than budget
+		slow := func() {
+			clock.Advance(time.Second)
+		}
+		assert.Equal(t, 1, s.Run(slow))
+
+		// not catching up the time run unthrottled
+		s.SetUnthrottled(false)
+		assert.Equal(t, 0, s.Run(step))
+	})
+}
+
+func TestScheduler_Measured(t *testing.T) {
+	s, clock := setup()
+	assert.Equal(t, float64(100), s.Measured())
+
+	for i := 0; i < 120; i++ {
+		clock.Advance(FrameDuration)
+		s.Run(func() {})
+	}
+	assert.InDelta(t, 100, s.Measured(), 1)
+
+	// paused every other frame
+	s, clock = setup()
+	for i := 0; i <= 60; i++ {
+		clock.Advance(FrameDuration)
+		if i%2 == 0 {
+			s.Run(func() {})
+		} else {
+			s.Reset()
+		}
+	}
+	assert.InDelta(t, 50, s.Measured(), 1)
+
+	s.SetUnthrottled(true)
+	for i := 0; i < 120; i++ {
+		s.Run(func() {
+			clock.Advance(FrameDuration / 4)
+		})
+	}
+	assert.InDelta(t, 400, s.Measured(), 1)
+}
+
+func TestScheduler_Speed(t *testing.T) {
+	tests := []struct {
+		speed float64
+		want  int
+	}{
+		{0.25, 14},
+		{0.5, 29},
+		{1, 59},
+		{2, 119},
+		{8, 477},
+	}
+	for _, tt := range tests {
+		s, clock := setup()
+		s.SetSpeed(tt.speed)
+		total := 0
+		for i := 0; i < 60; i++ {
+			clock.Advance(time.Second / 60)
+			total += s.Run(func() {})
+		}
+		assert.Equal(t, tt.want, total, "speed %v", tt.speed)
+	}
+}
+
+func TestScheduler_Pause(t *testing.T) {
+	s, clock := setup()
+	s.SetPaused(true)
+	clock.Advance(time.Second)
+	assert.Equal(t, 0, s.Run(func() {}))
+
+	s.Advance()
+	assert.Equal(t, 1, s.Run(func() {}))
+	assert.Equal(t, 0, s.Run(func() {}))
+	s.Advance()
+	s.Advance()
+	clock.Advance(time.Second)
+	assert.Equal(t, 2, s.Run(func() {}))
+
+	// not catching up the time paused
+	s.SetPaused(false)
+	assert.Equal(t, 0, s.Run(func() {}))
+	clock.Advance(FrameDuration)
+	assert.Equal(t, 1, s.Run(func() {}))
+
+	// ignored while running
+	s.Advance()
+	assert.Equal(t, 0, s.Run(func() {}))
+}
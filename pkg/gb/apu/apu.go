@@ -1,6 +1,9 @@
 package apu
 
-import "github.com/Teshima-Tatsuya/GoBoy/pkg/types"
+import (
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/state"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/types"
+)
 
 type APU struct {
 	NR10 byte
@@ -125,3 +128,12 @@ func (a *APU) Write(addr types.Addr, value byte) {
 		a.NR52 = value
 	}
 }
+
+// all registers are fixed size
+func (a *APU) SaveState(w *state.Writer) {
+	w.Write(a)
+}
+
+func (a *APU) LoadState(r *state.Reader) {
+	r.Read(a)
+}
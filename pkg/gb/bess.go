@@ -0,0 +1,327 @@
+package gb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/cartridge"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/cpu"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/gpu"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/types"
+)
+
+/*
+Best Effort Save State
+
+	Raw memory dump followed by blocks, and footer at the end of the file
+	 block:  identifier(4 bytes), size(uint32), payload
+	 footer: offset of the first block(uint32), "BESS"
+	@see https://github.com/LIJI32/SameBoy/blob/master/BESS.md
+*/
+const (
+	bessMagic        = "BESS"
+	bessMajorVersion = 1
+	bessMinorVersion = 1
+	bessCoreSize     = 0xD0
+	bessInfoSize     = 0x12
+	bessRTCSize      = 0x30
+	// DMG-B
+	bessModel = "GDB "
+)
+
+// CORE execution state
+const (
+	bessRunning byte = iota
+	bessHalted
+	bessStopped
+)
+
+var ErrInvalidBESS = errors.New("invalid BESS")
+
+// only DMG mode is supported, CGB mode needs banks and palettes
+var ErrBESSCGBMode = errors.New("BESS of CGB mode is not supported")
+
+type bessBlock struct {
+	id   string
+	data []byte
+}
+
+// memory dumped before blocks, CORE has size and offset of each
+type bessBuffer struct {
+	data []byte
+}
+
+func (b *bessBuffer) put(data []byte) (size, offset uint32) {
+	offset = uint32(len(b.data))
+	b.data = append(b.data, data...)
+	return uint32(len(data)), offset
+}
+
+func (gb *GB) wram() []byte {
+	return append(append([]byte(nil), gb.bus.WRAM.Buf[:0x1000]...), gb.bus.WRAM2.Buf[:0x1000]...)
+}
+
+func (gb *GB) oam() []byte {
+	oam := make([]byte, 0xA0)
+	for i := range oam {
+		oam[i] = gb.bus.ReadByte(0xFE00 + types.Addr(i))
+	}
+	return oam
+}
+
+// ExportBESS writes BESS save state which can be loaded by other emulators
+func (gb *GB) ExportBESS(w io.Writer) error {
+	if gb.Cartridge.CGBFlag {
+		return ErrBESSCGBMode
+	}
+	var mem bessBuffer
+	var core bytes.Buffer
+	le := binary.LittleEndian
+
+	wramSize, wramOffset := mem.put(gb.wram())
+	vramSize, vramOffset := mem.put(gb.bus.VRAM.Buf[:gpu.VRAMBankSize])
+	mbcSize, mbcOffset := mem.put(gb.Cartridge.RAM())
+	oamSize, oamOffset := mem.put(gb.oam())
+	hramSize, hramOffset := mem.put(gb.bus.HRAM.Buf[:0x7F])
+
+	reg := gb.cpu.Reg
+	execState := bessRunning
+	if gb.cpu.Halt {
+		execState = bessHalted
+	}
+	if gb.cpu.Stop {
+		execState = bessStopped
+	}
+
+	binary.Write(&core, le, []uint16{bessMajorVersion, bessMinorVersion})
+	core.WriteString(bessModel)
+	binary.Write(&core, le, []types.Addr{reg.PC, reg.AF(), reg.BC(), reg.DE(), reg.HL(), reg.SP})
+	ime := byte(0)
+	if gb.irq.IME {
+		ime = 1
+	}
+	core.Write([]byte{ime, gb.irq.IE, execState, 0x00})
+	for addr := types.Addr(0xFF00); addr < 0xFF80; addr++ {
+		core.WriteByte(gb.bus.ReadByte(addr))
+	}
+	binary.Write(&core, le, []uint32{
+		wramSize, wramOffset,
+		vramSize, vramOffset,
+		mbcSize, mbcOffset,
+		oamSize, oamOffset,
+		hramSize, hramOffset,
+		// no CGB palettes
+		0, 0,
+		0, 0,
+	})
+
+	info := make([]byte, bessInfoSize)
+	for i := range info {
+		info[i] = gb.Cartridge.ReadByte(0x0134 + types.Addr(i))
+	}
+	// global checksum
+	info[0x10] = gb.Cartridge.ReadByte(0x014E)
+	info[0x11] = gb.Cartridge.ReadByte(0x014F)
+
+	var mbc bytes.Buffer
+	for _, r := range gb.Cartridge.MBC.Registers() {
+		binary.Write(&mbc, le, r.Addr)
+		mbc.WriteByte(r.Value)
+	}
+
+	blocks := []bessBlock{
+		{"NAME", []byte("GoBoy")},
+		{"INFO", info},
+		{"CORE", core.Bytes()},
+	}
+	if mbc.Len() > 0 {
+		blocks = append(blocks, bessBlock{"MBC ", mbc.Bytes()})
+	}
+	if m, ok := gb.Cartridge.MBC.(*cartridge.MBC3); ok && m.RTC != nil {
+		blocks = append(blocks, bessBlock{"RTC ", m.RTC.Dump()})
+	}
+	blocks = append(blocks, bessBlock{"END ", nil})
+
+	out := bytes.NewBuffer(mem.data)
+	for _, b := range blocks {
+		out.WriteString(b.id)
+		binary.Write(out, le, uint32(len(b.data)))
+		out.Write(b.data)
+	}
+	binary.Write(out, le, uint32(len(mem.data)))
+	out.WriteString(bessMagic)
+
+	_, err := out.WriteTo(w)
+	return err
+}
+
+func parseBESS(data []byte) ([]bessBlock, error) {
+	le := binary.LittleEndian
+	if len(data) < 8 || string(data[len(data)-4:]) != bessMagic {
+		return nil, fmt.Errorf("%w: footer not found", ErrInvalidBESS)
+	}
+
+	offset := int(le.Uint32(data[len(data)-8:]))
+	end := len(data) - 8
+	var blocks []bessBlock
+	for {
+		if offset+8 > end {
+			return nil, fmt.Errorf("%w: END block not found", ErrInvalidBESS)
+		}
+		id := string(data[offset : offset+4])
+		size := int(le.Uint32(data[offset+4:]))
+		offset += 8
+		if offset+size > end {
+			return nil, fmt.Errorf("%w: block %q exceeds file", ErrInvalidBESS, id)
+		}
+		if id == "END " {
+			return blocks, nil
+		}
+		blocks = append(blocks, bessBlock{id, data[offset : offset+size]})
+		offset += size
+	}
+}
+
+// ImportBESS loads BESS save state of the same game
+// Unknown blocks are ignored as the specification says
+func (gb *GB) ImportBESS(r io.Reader) error {
+	if gb.Cartridge.CGBFlag {
+		return ErrBESSCGBMode
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	blocks, err := parseBESS(data)
+	if err != nil {
+		return err
+	}
+
+	// CORE must be the first block, except NAME and INFO
+	var core []byte
+	for _, b := range blocks {
+		if b.id == "CORE" {
+			core = b.data
+			break
+		}
+		if b.id != "NAME" && b.id != "INFO" {
+			return fmt.Errorf("%w: CORE must be the first block", ErrInvalidBESS)
+		}
+		if b.id == "INFO" {
+			if len(b.data) != bessInfoSize {
+				return fmt.Errorf("%w: INFO size %d", ErrInvalidBESS, len(b.data))
+			}
+			title := gb.title()
+			if !bytes.Equal(b.data[:0x0F], title[:0x0F]) {
+				return fmt.Errorf("%w: state is for %q", ErrInvalidBESS, bytes.TrimRight(b.data[:0x10], "\x00"))
+			}
+		}
+	}
+	if len(core) < bessCoreSize {
+		return fmt.Errorf("%w: CORE block is too short", ErrInvalidBESS)
+	}
+
+	le := binary.LittleEndian
+	if major := le.Uint16(core[0x00:]); major != bessMajorVersion {
+		return fmt.Errorf("%w: major version %d is not supported", ErrInvalidBESS, major)
+	}
+	if model := core[0x04:0x08]; model[0] != 'G' || model[1] == 'C' || model[1] == 'A' {
+		return fmt.Errorf("%w: model %q is not supported", ErrInvalidBESS, model)
+	}
+
+	// validate memory before restoring anything
+	mem := make([][]byte, 5)
+	for i := range mem {
+		size := int(le.Uint32(core[0x98+i*8:]))
+		offset := int(le.Uint32(core[0x9C+i*8:]))
+		if offset+size > len(data) {
+			return fmt.Errorf("%w: memory exceeds file", ErrInvalidBESS)
+		}
+		mem[i] = data[offset : offset+size]
+	}
+	for _, b := range blocks {
+		if b.id == "MBC " && len(b.data)%3 != 0 {
+			return fmt.Errorf("%w: MBC size %d", ErrInvalidBESS, len(b.data))
+		}
+	}
+
+	gb.restoreBESSCore(core)
+
+	// WRAM, VRAM, MBC RAM, OAM, HRAM
+	wram := mem[0]
+	n := copy(gb.bus.WRAM.Buf[:0x1000], wram)
+	copy(gb.bus.WRAM2.Buf[:0x1000], wram[n:])
+	copy(gb.bus.VRAM.Buf[:gpu.VRAMBankSize], mem[1])
+	copy(gb.Cartridge.RAM(), mem[2])
+	for i, v := range mem[3] {
+		if i < 0xA0 {
+			gb.bus.WriteByte(0xFE00+types.Addr(i), v)
+		}
+	}
+	copy(gb.bus.HRAM.Buf[:0x7F], mem[4])
+
+	for _, b := range blocks {
+		switch b.id {
+		case "MBC ":
+			for i := 0; i < len(b.data); i += 3 {
+				gb.Cartridge.WriteByte(types.Addr(le.Uint16(b.data[i:])), b.data[i+2])
+			}
+		case "RTC ":
+			m, ok := gb.Cartridge.MBC.(*cartridge.MBC3)
+			if ok && m.RTC != nil && len(b.data) == bessRTCSize {
+				m.RTC.Restore(b.data)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (gb *GB) restoreBESSCore(core []byte) {
+	le := binary.LittleEndian
+	reg := &gb.cpu.Reg
+	reg.PC = types.Addr(le.Uint16(core[0x08:]))
+	af := le.Uint16(core[0x0A:])
+	// lower 4 bits of F are always 0
+	reg.R[cpu.A], reg.R[cpu.F] = byte(af>>8), byte(af)&0xF0
+	bc := le.Uint16(core[0x0C:])
+	reg.R[cpu.B], reg.R[cpu.C] = byte(bc>>8), byte(bc)
+	de := le.Uint16(core[0x0E:])
+	reg.R[cpu.D], reg.R[cpu.E] = byte(de>>8), byte(de)
+	hl := le.Uint16(core[0x10:])
+	reg.R[cpu.H], reg.R[cpu.L] = byte(hl>>8), byte(hl)
+	reg.SP = types.Addr(le.Uint16(core[0x12:]))
+
+	gb.irq.IME = core[0x14] != 0
+	gb.irq.IE = core[0x15]
+	gb.cpu.Halt = core[0x16] == bessHalted
+	gb.cpu.Stop = core[0x16] == bessStopped
+
+	regs := core[0x18 : 0x18+0x80]
+	for i, v := range regs {
+		addr := types.Addr(0xFF00 + i)
+		switch addr {
+		case 0xFF04:
+			// writing DIV resets it
+			gb.timer.DIV = v
+		case 0xFF0F:
+			gb.irq.IF = v & 0x1F
+		case 0xFF41:
+			// mode and coincidence bits are read only
+			gb.gpu.LCDS.Data = v & 0x7F
+		case 0xFF44:
+			// LY is read only
+			gb.gpu.Scroll.LY = v
+		case 0xFF46:
+			// writing DMA starts the transfer
+			gb.gpu.DMA = v
+		case 0xFF50:
+			// boot ROM is not supported
+		default:
+			gb.bus.WriteByte(addr, v)
+		}
+	}
+}
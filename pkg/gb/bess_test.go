@@ -0,0 +1,264 @@
+package gb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"testing"
+
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/cpu"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// newBESS builds BESS file with no memory dumped
+func newBESS(blocks ...bessBlock) []byte {
+	var buf bytes.Buffer
+	for _, b := range blocks {
+		buf.WriteString(b.id)
+		binary.Write(&buf, binary.LittleEndian, uint32(len(b.data)))
+		buf.Write(b.data)
+	}
+	buf.WriteString("END ")
+	binary.Write(&buf, binary.LittleEndian, uint32(0))
+	binary.Write(&buf, binary.LittleEndian, uint32(0))
+	buf.WriteString(bessMagic)
+	return buf.Bytes()
+}
+
+func newBESSCore() []byte {
+	core := make([]byte, bessCoreSize)
+	le := binary.LittleEndian
+	le.PutUint16(core[0x00:], bessMajorVersion)
+	le.PutUint16(core[0x02:], bessMinorVersion)
+	copy(core[0x04:], "GD  ")
+	le.PutUint16(core[0x08:], 0x0150)
+	le.PutUint16(core[0x0A:], 0x12FF)
+	le.PutUint16(core[0x0C:], 0x3456)
+	le.PutUint16(core[0x0E:], 0x789A)
+	le.PutUint16(core[0x10:], 0xBCDE)
+	le.PutUint16(core[0x12:], 0xFFFE)
+	core[0x14] = 1
+	core[0x15] = 0x05
+	core[0x16] = bessHalted
+	// LCDC
+	core[0x18+0x40] = 0x91
+	// SCY
+	core[0x18+0x42] = 0x20
+	return core
+}
+
+// setupSameBoy makes GB of testdata/bess/fixture.gb with the state exported by SameBoy
+func setupSameBoy(t *testing.T) *GB {
+	romData, err := ioutil.ReadFile("testdata/bess/fixture.gb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile("testdata/bess/sameboy.s0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gb := setup(romData)
+	if err := gb.ImportBESS(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	return gb
+}
+
+func TestGB_BESS(t *testing.T) {
+	t.Run("import SameBoy", func(t *testing.T) {
+		gb := setupSameBoy(t)
+
+		reg := gb.cpu.Reg
+		// JR -2 of the main loop
+		assert.Equal(t, types.Addr(0x01B2), reg.PC)
+		assert.Equal(t, types.Addr(0xDFF0), reg.SP)
+		assert.Equal(t, types.Addr(0xDEA0), reg.AF())
+		assert.Equal(t, types.Addr(0x1234), reg.BC())
+		assert.Equal(t, types.Addr(0x5678), reg.DE())
+		assert.Equal(t, types.Addr(0x9ABC), reg.HL())
+		assert.False(t, gb.irq.IME)
+		assert.False(t, gb.cpu.Halt)
+
+		io := map[types.Addr]byte{
+			0xFF06: 0xAB,
+			0xFF07: 0xFC,
+			0xFF40: 0x91,
+			0xFF42: 0x12,
+			0xFF43: 0x34,
+			0xFF47: 0xE4,
+			0xFF48: 0xD2,
+			0xFFFF: 0x05,
+		}
+		for addr, want := range io {
+			assert.Equal(t, want, gb.ReadByte(addr), "0x%04X", addr)
+		}
+
+		// ROM bank 3 and RAM enabled by MBC block
+		assert.Equal(t, byte(3), gb.ReadByte(0x4000))
+		assert.Equal(t, byte(3), gb.ReadByte(0x7FFF))
+		assert.Equal(t, byte(0x5A), gb.ReadByte(0xA000))
+
+		mem := map[types.Addr]byte{
+			0xC000: 0xC3,
+			0xD134: 0x3D,
+			0x8010: 0x3C,
+			0xFE00: 0x42,
+			0xFF80: 0x77,
+		}
+		for addr, want := range mem {
+			assert.Equal(t, want, gb.bus.ReadByte(addr), "0x%04X", addr)
+		}
+
+		// keeps running the loop
+		gb.Step()
+		assert.Equal(t, types.Addr(0x01B2), gb.cpu.Reg.PC)
+	})
+
+	t.Run("export layout", func(t *testing.T) {
+		gb := setupSameBoy(t)
+		var buf bytes.Buffer
+		assert.NoError(t, gb.ExportBESS(&buf))
+		data := buf.Bytes()
+		le := binary.LittleEndian
+
+		// footer
+		assert.Equal(t, bessMagic, string(data[len(data)-4:]))
+		first := int(le.Uint32(data[len(data)-8:]))
+
+		var ids []string
+		blocks := map[string][]byte{}
+		for offset := first; ; {
+			id := string(data[offset : offset+4])
+			size := int(le.Uint32(data[offset+4:]))
+			ids = append(ids, id)
+			blocks[id] = data[offset+8 : offset+8+size]
+			offset += 8 + size
+			if id == "END " {
+				// footer follows END block
+				assert.Equal(t, len(data)-8, offset)
+				break
+			}
+		}
+		assert.Equal(t, []string{"NAME", "INFO", "CORE", "MBC ", "END "}, ids)
+
+		info := blocks["INFO"]
+		assert.Len(t, info, 0x12)
+		assert.Equal(t, "BESS FIXTURE", string(bytes.TrimRight(info[:0x10], "\x00")))
+		assert.Equal(t, []byte{gb.ReadByte(0x014E), gb.ReadByte(0x014F)}, info[0x10:])
+
+		core := blocks["CORE"]
+		assert.Len(t, core, 0xD0)
+		assert.Equal(t, uint16(1), le.Uint16(core[0x00:]))
+		assert.Equal(t, uint16(1), le.Uint16(core[0x02:]))
+		assert.Equal(t, "GDB ", string(core[0x04:0x08]))
+		for i, want := range []uint16{0x01B2, 0xDEA0, 0x1234, 0x5678, 0x9ABC, 0xDFF0} {
+			assert.Equal(t, want, le.Uint16(core[0x08+i*2:]))
+		}
+		assert.Equal(t, []byte{0x00, 0x05, bessRunning}, core[0x14:0x17])
+		assert.Equal(t, byte(0x34), core[0x18+0x43])
+
+		// size and offset of WRAM, VRAM, MBC RAM, OAM, HRAM, and no CGB palettes
+		sizes := []int{0x2000, 0x2000, 0x2000, 0xA0, 0x7F, 0, 0}
+		var want int
+		for i, size := range sizes {
+			assert.Equal(t, size, int(le.Uint32(core[0x98+i*8:])), "size of #%d", i)
+			if size == 0 {
+				continue
+			}
+			assert.Equal(t, want, int(le.Uint32(core[0x9C+i*8:])), "offset of #%d", i)
+			want += size
+		}
+		// memory is dumped before the first block
+		assert.Equal(t, want, first)
+		assert.Equal(t, byte(0x5A), data[0x4000])
+
+		// RAM enabled, ROM bank 3, simple banking mode in the same order as SameBoy
+		assert.Equal(t, []byte{0x00, 0x00, 0x0A, 0x00, 0x20, 0x03, 0x00, 0x40, 0x00, 0x00, 0x60, 0x00}, blocks["MBC "])
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		gb := setupFile(t, "helloworld/hello.gb")
+		for i := 0; i < 30; i++ {
+			gb.Step()
+		}
+
+		var buf bytes.Buffer
+		assert.NoError(t, gb.ExportBESS(&buf))
+		saved := buf.Bytes()
+		assert.Equal(t, bessMagic, string(saved[len(saved)-4:]))
+
+		for i := 0; i < 30; i++ {
+			gb.Step()
+		}
+		want, _ := gb.Display()
+
+		assert.NoError(t, gb.ImportBESS(bytes.NewReader(saved)))
+		for i := 0; i < 30; i++ {
+			gb.Step()
+		}
+		got, _ := gb.Display()
+		assert.Equal(t, want.Pix, got.Pix)
+	})
+
+	t.Run("MBC registers", func(t *testing.T) {
+		gb := setupFile(t, "mooneye-gb/emulator-only/mbc5/rom_1Mb.gb")
+		gb.Cartridge.WriteByte(0x2000, 0x05)
+		bank := gb.Cartridge.ReadByte(0x4000)
+
+		var buf bytes.Buffer
+		assert.NoError(t, gb.ExportBESS(&buf))
+
+		gb.Cartridge.WriteByte(0x2000, 0x01)
+		assert.NoError(t, gb.ImportBESS(&buf))
+		assert.Equal(t, bank, gb.Cartridge.ReadByte(0x4000))
+	})
+
+	t.Run("CORE", func(t *testing.T) {
+		gb := setupFile(t, "helloworld/hello.gb")
+		data := newBESS(
+			bessBlock{"NAME", []byte("SameBoy v0.14")},
+			bessBlock{"CORE", newBESSCore()},
+			bessBlock{"XXXX", []byte{0x01, 0x02}},
+		)
+		assert.NoError(t, gb.ImportBESS(bytes.NewReader(data)))
+
+		reg := gb.cpu.Reg
+		assert.Equal(t, types.Addr(0x0150), reg.PC)
+		assert.Equal(t, types.Addr(0xFFFE), reg.SP)
+		assert.Equal(t, byte(0x12), reg.R[cpu.A])
+		// lower 4 bits are ignored
+		assert.Equal(t, byte(0xF0), reg.R[cpu.F])
+		assert.Equal(t, types.Addr(0x3456), reg.BC())
+		assert.Equal(t, types.Addr(0x789A), reg.DE())
+		assert.Equal(t, types.Addr(0xBCDE), reg.HL())
+		assert.True(t, gb.irq.IME)
+		assert.Equal(t, byte(0x05), gb.irq.IE)
+		assert.True(t, gb.cpu.Halt)
+		assert.Equal(t, byte(0x91), gb.bus.ReadByte(0xFF40))
+		assert.Equal(t, byte(0x20), gb.bus.ReadByte(0xFF42))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		gb := setupFile(t, "helloworld/hello.gb")
+
+		assert.ErrorIs(t, gb.ImportBESS(bytes.NewReader([]byte("GoBoy"))), ErrInvalidBESS)
+
+		noEnd := newBESS(bessBlock{"CORE", newBESSCore()})
+		noEnd = append(noEnd[:len(noEnd)-16], noEnd[len(noEnd)-8:]...)
+		assert.ErrorIs(t, gb.ImportBESS(bytes.NewReader(noEnd)), ErrInvalidBESS)
+
+		notFirst := newBESS(bessBlock{"MBC ", nil}, bessBlock{"CORE", newBESSCore()})
+		assert.ErrorIs(t, gb.ImportBESS(bytes.NewReader(notFirst)), ErrInvalidBESS)
+
+		cgb := newBESSCore()
+		copy(cgb[0x04:], "CC  ")
+		assert.ErrorIs(t, gb.ImportBESS(bytes.NewReader(newBESS(bessBlock{"CORE", cgb}))), ErrInvalidBESS)
+
+		info := make([]byte, bessInfoSize)
+		copy(info, "OTHER GAME")
+		other := newBESS(bessBlock{"INFO", info}, bessBlock{"CORE", newBESSCore()})
+		assert.ErrorIs(t, gb.ImportBESS(bytes.NewReader(other)), ErrInvalidBESS)
+	})
+}
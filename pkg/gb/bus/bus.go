@@ -9,6 +9,7 @@ import (
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/pad"
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/serial"
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/timer"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/state"
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/types"
 )
 
@@ -26,40 +27,84 @@ type Bus struct {
 	serial *serial.Serial
 	gpu    *gpu.GPU
 	irq    *interrupt.IRQ
+
+	// CGB mode, VRAM has 2 banks and WRAM2 has 7 banks
+	cgb bool
+	// FF4F
+	vramBank byte
+	// FF70, 1-7
+	wramBank byte
+	// FF4D, bit 7 is the current speed and bit 0 arms switch by STOP
+	doubleSpeed bool
+	speedArmed  bool
 }
 
+// WRAM2 is switched by 4KB in CGB mode
+const wramBankSize = 0x1000
+
 func New(cart *cartridge.Cartridge, vram *memory.RAM, wram *memory.RAM, wram2 *memory.RAM, hram *memory.RAM, a *apu.APU, g *gpu.GPU, irq *interrupt.IRQ, pad *pad.Pad, timer *timer.Timer, serial *serial.Serial) *Bus {
 	eram := memory.NewRAM(0x2000)
 	oam := memory.NewRAM(0x00A0)
 	return &Bus{
-		Cart:   cart,
-		VRAM:   vram,
-		WRAM:   wram,
-		WRAM2:  wram2,
-		HRAM:   hram,
-		ERAM:   eram,
-		oam:    oam,
-		apu:    a,
-		gpu:    g,
-		irq:    irq,
-		pad:    pad,
-		timer:  timer,
-		serial: serial,
+		Cart:     cart,
+		VRAM:     vram,
+		WRAM:     wram,
+		WRAM2:    wram2,
+		HRAM:     hram,
+		ERAM:     eram,
+		oam:      oam,
+		apu:      a,
+		gpu:      g,
+		irq:      irq,
+		pad:      pad,
+		timer:    timer,
+		serial:   serial,
+		wramBank: 1,
 	}
 }
 
+// SetCGBMode enables VRAM and WRAM banks, and registers of CGB
+func (b *Bus) SetCGBMode() {
+	b.cgb = true
+}
+
+// DoubleSpeed reports CPU, timer and serial run twice as fast as PPU
+func (b *Bus) DoubleSpeed() bool {
+	return b.doubleSpeed
+}
+
+// SwitchSpeed is called by STOP, and switches speed if armed by KEY1
+// DIV is reset by STOP itself
+// @see https://gbdev.io/pandocs/CGB_Registers.html#ff4d--key1-cgb-mode-only-prepare-speed-switch
+func (b *Bus) SwitchSpeed() bool {
+	if !b.cgb || !b.speedArmed {
+		return false
+	}
+	b.doubleSpeed = !b.doubleSpeed
+	b.speedArmed = false
+	return true
+}
+
+func (b *Bus) vramAddr(addr types.Addr) types.Addr {
+	return types.Addr(b.vramBank)*gpu.VRAMBankSize + addr - 0x8000
+}
+
+func (b *Bus) wramAddr(addr types.Addr) types.Addr {
+	return types.Addr(b.wramBank-1)*wramBankSize + addr - 0xD000
+}
+
 func (b *Bus) ReadByte(addr types.Addr) byte {
 	switch {
 	case addr >= 0x0000 && addr <= 0x7FFF:
 		return b.Cart.ReadByte(addr)
 	case addr >= 0x8000 && addr <= 0x9FFF:
-		return b.VRAM.Read(addr - 0x8000)
+		return b.VRAM.Read(b.vramAddr(addr))
 	case addr >= 0xA000 && addr <= 0xBFFF:
 		return b.Cart.ReadByte(addr)
 	case addr >= 0xC000 && addr <= 0xCFFF:
 		return b.WRAM.Read(addr - 0xC000)
 	case addr >= 0xD000 && addr <= 0xDFFF:
-		return b.WRAM2.Read(addr - 0xD000)
+		return b.WRAM2.Read(b.wramAddr(addr))
 	case addr >= 0xE000 && addr <= 0xFDFF:
 		return b.ERAM.Read(addr - 0xE000)
 	case addr >= 0xFE00 && addr <= 0xFE9F:
@@ -78,6 +123,26 @@ func (b *Bus) ReadByte(addr types.Addr) byte {
 		return b.apu.Read(addr - 0xFF00)
 	case addr >= 0xFF40 && addr <= 0xFF4B:
 		return b.gpu.Read(addr - 0xFF00)
+	case addr == 0xFF4D && b.cgb:
+		// bit 1-6 are unused
+		v := byte(0x7E)
+		if b.doubleSpeed {
+			v |= 0x80
+		}
+		if b.speedArmed {
+			v |= 0x01
+		}
+		return v
+	case addr == 0xFF4F && b.cgb:
+		// bit 1-7 are unused
+		return 0xFE | b.vramBank
+	case addr >= 0xFF51 && addr <= 0xFF55 && b.cgb:
+		return b.gpu.Read(addr - 0xFF00)
+	case addr >= 0xFF68 && addr <= 0xFF6B && b.cgb:
+		return b.gpu.Read(addr - 0xFF00)
+	case addr == 0xFF70 && b.cgb:
+		// bit 3-7 are unused
+		return 0xF8 | b.wramBank
 	case addr >= 0xFF80 && addr <= 0xFFFE:
 		return b.HRAM.Read(addr - 0xFF80)
 	default:
@@ -96,13 +161,14 @@ func (b *Bus) WriteByte(addr types.Addr, value byte) {
 	case addr >= 0x0000 && addr <= 0x7FFF:
 		b.Cart.WriteByte(addr, value)
 	case addr >= 0x8000 && addr <= 0x9FFF:
-		b.VRAM.Write(addr-0x8000, value)
+		b.VRAM.Write(b.vramAddr(addr), value)
+		b.gpu.InvalidateTile(addr, b.vramBank)
 	case addr >= 0xA000 && addr <= 0xBFFF:
 		b.Cart.WriteByte(addr, value)
 	case addr >= 0xC000 && addr <= 0xCFFF:
 		b.WRAM.Write(addr-0xC000, value)
 	case addr >= 0xD000 && addr <= 0xDFFF:
-		b.WRAM2.Write(addr-0xD000, value)
+		b.WRAM2.Write(b.wramAddr(addr), value)
 	case addr >= 0xE000 && addr <= 0xFDFF:
 		b.ERAM.Write(addr-0xE000, value)
 	case addr >= 0xFE00 && addr <= 0xFE9F:
@@ -121,9 +187,48 @@ func (b *Bus) WriteByte(addr types.Addr, value byte) {
 		b.apu.Write(addr-0xFF00, value)
 	case addr >= 0xFF40 && addr <= 0xFF4B:
 		b.gpu.Write(addr-0xFF00, value)
+	case addr == 0xFF4D && b.cgb:
+		b.speedArmed = value&0x01 == 1
+	case addr == 0xFF4F && b.cgb:
+		b.vramBank = value & 0x01
+	case addr >= 0xFF51 && addr <= 0xFF55 && b.cgb:
+		b.gpu.Write(addr-0xFF00, value)
+	case addr >= 0xFF68 && addr <= 0xFF6B && b.cgb:
+		b.gpu.Write(addr-0xFF00, value)
+	case addr == 0xFF70 && b.cgb:
+		// bank 0 selects bank 1
+		b.wramBank = value & 0x07
+		if b.wramBank == 0 {
+			b.wramBank = 1
+		}
 	case addr >= 0xFF80 && addr <= 0xFFFE:
 		b.HRAM.Write(addr-0xFF80, value)
 	default:
 		// debug.Fatal("Addr:0x%4x is not implemented", addr)
 	}
 }
+
+// other components are saved by themselves
+func (b *Bus) SaveState(w *state.Writer) {
+	b.VRAM.SaveState(w)
+	b.WRAM.SaveState(w)
+	b.WRAM2.SaveState(w)
+	b.HRAM.SaveState(w)
+	b.ERAM.SaveState(w)
+	b.oam.SaveState(w)
+	w.Write([]byte{b.vramBank, b.wramBank})
+	w.Write([]bool{b.doubleSpeed, b.speedArmed})
+}
+
+func (b *Bus) LoadState(r *state.Reader) {
+	b.VRAM.LoadState(r)
+	b.WRAM.LoadState(r)
+	b.WRAM2.LoadState(r)
+	b.HRAM.LoadState(r)
+	b.ERAM.LoadState(r)
+	b.oam.LoadState(r)
+	r.Read(&b.vramBank)
+	r.Read(&b.wramBank)
+	r.Read(&b.doubleSpeed)
+	r.Read(&b.speedArmed)
+}
@@ -1,7 +1,10 @@
 package cartridge
 
 import (
+	"bytes"
+
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/debug"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/state"
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/types"
 )
 
@@ -57,6 +60,14 @@ type Cartridge struct {
 	Type    byte
 	MBC     MBC
 	Mode    byte
+	// external RAM is kept by battery
+	HasBattery bool
+
+	storage Storage
+	// last data written to storage
+	saved []byte
+
+	rumbleHandlers []func(bool)
 }
 
 func New(romData []byte) *Cartridge {
@@ -72,6 +83,7 @@ func New(romData []byte) *Cartridge {
 		CGBFlag:      cgbflag,
 		SGBFlag:      sgbflag,
 		Type:         getType(romData[0x0147]),
+		HasBattery:   hasBattery(romData[0x0147]),
 	}
 
 	switch c.Type {
@@ -79,6 +91,14 @@ func New(romData []byte) *Cartridge {
 		c.MBC = NewMBC0(romData)
 	case TYPE_MBC1:
 		c.MBC = NewMBC1(romData, getRamSize(romData[0x0149]))
+	case TYPE_MBC2:
+		c.MBC = NewMBC2(romData)
+	case TYPE_MBC3:
+		c.MBC = NewMBC3(romData, getRamSize(romData[0x0149]), hasRTC(romData[0x0147]))
+	case TYPE_MBC5:
+		m := NewMBC5(romData, getRamSize(romData[0x0149]), hasRumble(romData[0x0147]))
+		m.SetRumbleHandler(c.notifyRumble)
+		c.MBC = m
 	default:
 		debug.Fatal("Non Supported Cartridge type %d", c.Type)
 	}
@@ -133,6 +153,26 @@ func getType(t byte) byte {
 	return 0
 }
 
+// @see https://gbdev.io/pandocs/The_Cartridge_Header.html#0147---cartridge-type
+func hasBattery(t byte) bool {
+	switch t {
+	case 0x03, 0x06, 0x09, 0x0D, 0x0F, 0x10, 0x13, 0x1B, 0x1E, 0x22, 0xFF:
+		return true
+	}
+
+	return false
+}
+
+// MBC3+TIMER+BATTERY, MBC3+TIMER+RAM+BATTERY
+func hasRTC(t byte) bool {
+	return t == 0x0F || t == 0x10
+}
+
+// MBC5+RUMBLE, MBC5+RUMBLE+RAM, MBC5+RUMBLE+RAM+BATTERY
+func hasRumble(t byte) bool {
+	return t == 0x1C || t == 0x1D || t == 0x1E
+}
+
 func validateCheckSum(romData []byte) bool {
 	x := byte(0x00)
 
@@ -155,3 +195,87 @@ func (c *Cartridge) ReadByte(addr types.Addr) byte {
 func (c *Cartridge) WriteByte(addr types.Addr, value byte) {
 	c.MBC.Write(addr, value)
 }
+
+// OnRumble registers an observer of the rumble motor
+// f is called with true when the motor starts and false when it stops
+func (c *Cartridge) OnRumble(f func(bool)) {
+	c.rumbleHandlers = append(c.rumbleHandlers, f)
+}
+
+func (c *Cartridge) notifyRumble(on bool) {
+	for _, f := range c.rumbleHandlers {
+		f(on)
+	}
+}
+
+// SetStorage loads external RAM from s,
+// and s is used by Flush after that
+// It does nothing if the cartridge has no battery
+func (c *Cartridge) SetStorage(s Storage) error {
+	m, ok := c.MBC.(BatteryBacked)
+	if !c.HasBattery || !ok {
+		return nil
+	}
+
+	c.storage = s
+	data, err := s.Load()
+	if err != nil {
+		return err
+	}
+	if data != nil {
+		m.Restore(data)
+	}
+	c.saved = m.Dump()
+
+	return nil
+}
+
+// Flush writes external RAM to storage
+// when it has been changed from last Flush
+func (c *Cartridge) Flush() error {
+	if c.storage == nil {
+		return nil
+	}
+
+	data := c.MBC.(BatteryBacked).Dump()
+	if bytes.Equal(data, c.saved) {
+		return nil
+	}
+	if err := c.storage.Save(data); err != nil {
+		return err
+	}
+	c.saved = data
+
+	return nil
+}
+
+func (c *Cartridge) SaveState(w *state.Writer) {
+	c.MBC.SaveState(w)
+}
+
+func (c *Cartridge) LoadState(r *state.Reader) {
+	c.MBC.LoadState(r)
+}
+
+// RAM returns external RAM of the cartridge, nil if it has no RAM
+// RTC registers of MBC3 are not included
+func (c *Cartridge) RAM() []byte {
+	switch m := c.MBC.(type) {
+	case *MBC1:
+		if m.RAM != nil {
+			return m.RAM.Buf
+		}
+	case *MBC2:
+		return m.RAM.Buf
+	case *MBC3:
+		if m.RAM != nil {
+			return m.RAM.Buf
+		}
+	case *MBC5:
+		if m.RAM != nil {
+			return m.RAM.Buf
+		}
+	}
+
+	return nil
+}
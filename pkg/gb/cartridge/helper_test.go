@@ -0,0 +1,35 @@
+package cartridge
+
+import (
+	"time"
+
+	"github.com/Teshima-Tatsuya/GoBoy/test/mock"
+)
+
+// every bank starts with its bank number
+func newBankedROM(banks int) []byte {
+	romData := make([]byte, banks*0x4000)
+	for i := 0; i < banks; i++ {
+		romData[i*0x4000] = byte(i)
+		romData[i*0x4000+1] = byte(i >> 8)
+	}
+	return romData
+}
+
+func newCartridge(cartType byte, ramType byte) *Cartridge {
+	romData := newBankedROM(4)
+	romData[0x0147] = cartType
+	romData[0x0149] = ramType
+
+	return New(romData)
+}
+
+func setupMBC3() (*MBC3, *mock.MockClock) {
+	clock := mock.NewMockClock(time.Unix(0, 0))
+	m := NewMBC3(newBankedROM(128), RAM_32KB, true)
+	m.SetClock(clock.Now)
+	// enable RAM and RTC
+	m.Write(0x0000, 0x0A)
+
+	return m, clock
+}
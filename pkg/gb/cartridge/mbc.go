@@ -1,6 +1,9 @@
 package cartridge
 
-import "github.com/Teshima-Tatsuya/GoBoy/pkg/types"
+import (
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/state"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/types"
+)
 
 // Memory Bank Controller
 // Which provides BANK switch
@@ -10,4 +13,28 @@ type MBC interface {
 	Write(addr types.Addr, value byte)
 	SwitchROMBank(bank uint16)
 	SwitchRAMBank(bank uint8)
+	// writes which reproduce current bank state
+	Registers() []RegisterWrite
+	// bank registers and RAM
+	state.Component
+}
+
+// RegisterWrite is a write to MBC register
+type RegisterWrite struct {
+	Addr  types.Addr
+	Value byte
+}
+
+func ramEnableValue(enable bool) byte {
+	if enable {
+		return 0x0A
+	}
+	return 0x00
+}
+
+// MBC which has battery backed external RAM
+// Dump and Restore use the raw .sav format
+type BatteryBacked interface {
+	Dump() []byte
+	Restore(data []byte)
 }
@@ -2,6 +2,7 @@ package cartridge
 
 import (
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/memory"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/state"
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/types"
 )
 
@@ -30,3 +31,16 @@ func (m *MBC0) SwitchROMBank(bank uint16) {
 // nop
 func (m *MBC0) SwitchRAMBank(bank uint8) {
 }
+
+// nop
+func (m *MBC0) SaveState(w *state.Writer) {
+}
+
+// nop
+func (m *MBC0) LoadState(r *state.Reader) {
+}
+
+// nop
+func (m *MBC0) Registers() []RegisterWrite {
+	return nil
+}
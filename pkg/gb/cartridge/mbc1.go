@@ -1,10 +1,12 @@
 package cartridge
 
 import (
+	"bytes"
 	"fmt"
 
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/debug"
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/memory"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/state"
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/types"
 )
 
@@ -17,12 +19,14 @@ const (
 type MBC1 struct {
 	ROM *memory.ROM
 	RAM *memory.RAM
-	// max 2MB
+	// BANK1, lower 5 bits of ROM bank
 	romBank uint8
-	// max 32KiB
+	// BANK2, upper 2 bits of ROM bank, or RAM bank in mode 1
 	ramBank   uint8
 	ramEnable bool
 	mode      uint8
+	// MBC1M wires BANK2 to bits 4-5 of ROM bank for multi-game compilations
+	multicart bool
 }
 
 func NewMBC1(romData []byte, ramSize int) *MBC1 {
@@ -41,22 +45,32 @@ func NewMBC1(romData []byte, ramSize int) *MBC1 {
 		m.ramBank = 0
 		m.ramEnable = false
 	}
+	m.multicart = isMulticart(romData)
 
 	return m
 }
 
+// MBC1M has 1MB ROM with Nintendo logo in bank 0x10, where the second game starts
+// @see https://gbdev.io/pandocs/MBC1.html#mbc1m-1-mib-multi-game-compilation-carts
+func isMulticart(romData []byte) bool {
+	if len(romData) != ROM_1024KB {
+		return false
+	}
+	logo := romData[0x0104:0x0134]
+	return bytes.Equal(logo, romData[0x10*0x4000+0x0104:0x10*0x4000+0x0134])
+}
+
 func (m *MBC1) Read(addr types.Addr) byte {
 	// @see https://gbdev.io/pandocs/MBC1.html
 	// Implement Read address range
 	switch {
 	case addr < 0x4000:
-		return m.ROM.Read(uint32(addr))
+		return m.ROM.Read(m.romAddr(m.zeroBank(), addr))
 	case 0x4000 <= addr && addr < 0x8000:
-		return m.ROM.Read(uint32(m.romBank)*0x4000 + uint32(addr) - 0x4000)
+		return m.ROM.Read(m.romAddr(m.hiBank()|m.lowBank(), addr-0x4000))
 	case 0xA000 <= addr && addr < 0xC000:
-		if m.ramEnable {
-			addr = types.Addr(uint16(addr) + uint16(m.ramBank)*0x2000 - 0xA000)
-			return m.RAM.Read(addr)
+		if m.ramEnable && m.RAM != nil {
+			return m.RAM.Read(m.ramAddr(addr))
 		} else {
 			return 0xFF
 		}
@@ -71,48 +85,120 @@ func (m *MBC1) Write(addr types.Addr, value byte) {
 	// Implement Write address range
 	switch {
 	case addr < 0x2000:
-		if value == 0x00 {
-			m.ramEnable = false
-		} else if value == 0x0A {
-			m.ramEnable = true
-		}
+		// any value with 0x0A in lower 4 bits enables RAM
+		m.ramEnable = value&0x0F == 0x0A
 	case 0x2000 <= addr && addr < 0x4000:
 		m.SwitchROMBank(uint16(value & 0x1F))
 	case 0x4000 <= addr && addr < 0x6000:
-		if m.mode == SimpleROMBankingMode {
-			m.SwitchHiROMBank(value)
-		} else if m.mode == RAMBankingModeAdvancedROMBankingMode {
-			// lower 2bit
-			m.SwitchRAMBank(value & 0x03)
-		}
+		// lower 2bit
+		m.SwitchRAMBank(value & 0x03)
 	case 0x6000 <= addr && addr < 0x8000:
-		m.mode = value
+		m.mode = value & 0x01
 	case 0xA000 <= addr && addr < 0xC000:
-		if m.ramEnable {
-			addr = addr + types.Addr(m.ramBank)*0x2000 - 0xA000
-			m.RAM.Write(addr, value)
+		if m.ramEnable && m.RAM != nil {
+			m.RAM.Write(m.ramAddr(addr), value)
 		}
 	}
 }
 
+// bank of 0x0000-0x3FFF, BANK2 is applied in mode 1
+func (m *MBC1) zeroBank() uint8 {
+	if m.mode == SimpleROMBankingMode {
+		return 0
+	}
+	return m.hiBank()
+}
+
+// BANK2 as upper bits of ROM bank
+func (m *MBC1) hiBank() uint8 {
+	if m.multicart {
+		return m.ramBank << 4
+	}
+	return m.ramBank << 5
+}
+
+// BANK1 as lower bits of ROM bank, MBC1M doesn't use bit 4
+func (m *MBC1) lowBank() uint8 {
+	if m.multicart {
+		return m.romBank & 0x0F
+	}
+	return m.romBank
+}
+
+// BANK2 selects RAM bank only in mode 1
+func (m *MBC1) currentRAMBank() uint8 {
+	if m.mode == SimpleROMBankingMode {
+		return 0
+	}
+	return m.ramBank
+}
+
+// RAM smaller than 32KB is mirrored
+func (m *MBC1) ramAddr(addr types.Addr) types.Addr {
+	offset := int(m.currentRAMBank())*0x2000 + int(addr) - 0xA000
+	return types.Addr(offset % len(m.RAM.Buf))
+}
+
+// banks over ROM size are mirrored
+func (m *MBC1) romAddr(bank uint8, addr types.Addr) uint32 {
+	offset := uint32(bank)*0x4000 + uint32(addr)
+	return offset % uint32(len(m.ROM.Buf))
+}
+
+// bank is written to BANK1, 0x00 is treated as 0x01
+// BANK2 is not considered, so 0x20, 0x40 and 0x60 can't be selected
 func (m *MBC1) SwitchROMBank(bank uint16) {
-	if bank == 0x00 || bank == 0x20 || bank == 0x40 || bank == 0x60 {
+	if bank == 0x00 {
 		bank++
 	}
 
 	m.romBank = uint8(bank)
 }
 
-func (m *MBC1) SwitchHiROMBank(value byte) {
-	// clear Hi bit
-	m.romBank &= 0x1F
+// bank is written to BANK2
+func (m *MBC1) SwitchRAMBank(bank uint8) {
+	m.ramBank = bank
+}
+
+func (m *MBC1) Dump() []byte {
+	if m.RAM == nil {
+		return nil
+	}
+	return append([]byte(nil), m.RAM.Buf...)
+}
+
+func (m *MBC1) Restore(data []byte) {
+	if m.RAM == nil {
+		return
+	}
+	copy(m.RAM.Buf, data)
+}
 
-	// clear Low bit
-	value &= 0xE0
+func (m *MBC1) SaveState(w *state.Writer) {
+	w.Write(m.romBank)
+	w.Write(m.ramBank)
+	w.Write(m.ramEnable)
+	w.Write(m.mode)
+	if m.RAM != nil {
+		m.RAM.SaveState(w)
+	}
+}
 
-	m.romBank |= value
+func (m *MBC1) LoadState(r *state.Reader) {
+	r.Read(&m.romBank)
+	r.Read(&m.ramBank)
+	r.Read(&m.ramEnable)
+	r.Read(&m.mode)
+	if m.RAM != nil {
+		m.RAM.LoadState(r)
+	}
 }
 
-func (m *MBC1) SwitchRAMBank(bank uint8) {
-	m.ramBank = bank
+func (m *MBC1) Registers() []RegisterWrite {
+	return []RegisterWrite{
+		{0x0000, ramEnableValue(m.ramEnable)},
+		{0x2000, m.romBank},
+		{0x4000, m.ramBank},
+		{0x6000, m.mode},
+	}
 }
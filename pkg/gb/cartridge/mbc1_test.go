@@ -1 +1,161 @@
 package cartridge
+
+import (
+	"testing"
+
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMBC1_SwitchROMBank(t *testing.T) {
+	tests := []struct {
+		name  string
+		banks int
+		bank1 byte
+		bank2 byte
+		mode  byte
+		// bank of 0x0000-0x3FFF and 0x4000-0x7FFF
+		want0 byte
+		want4 byte
+	}{
+		{name: "bank 0x00 is 0x01", banks: 128, bank1: 0x00, want0: 0x00, want4: 0x01},
+		{name: "bank 0x1F", banks: 128, bank1: 0x1F, want0: 0x00, want4: 0x1F},
+		{name: "upper bits of BANK1 are ignored", banks: 128, bank1: 0xE3, want0: 0x00, want4: 0x03},
+		{name: "bank 0x21", banks: 128, bank1: 0x01, bank2: 0x01, want0: 0x00, want4: 0x21},
+		{name: "bank 0x20 is 0x21", banks: 128, bank1: 0x00, bank2: 0x01, want0: 0x00, want4: 0x21},
+		{name: "bank 0x40 is 0x41", banks: 128, bank1: 0x00, bank2: 0x02, want0: 0x00, want4: 0x41},
+		{name: "bank 0x60 is 0x61", banks: 128, bank1: 0x00, bank2: 0x03, want0: 0x00, want4: 0x61},
+		{name: "upper bits of BANK2 are ignored", banks: 128, bank1: 0x05, bank2: 0xFE, want0: 0x00, want4: 0x45},
+		{name: "mode 1 bank 0x20", banks: 128, bank1: 0x00, bank2: 0x01, mode: 1, want0: 0x20, want4: 0x21},
+		{name: "mode 1 bank 0x40", banks: 128, bank1: 0x02, bank2: 0x02, mode: 1, want0: 0x40, want4: 0x42},
+		{name: "mode 1 bank 0x60", banks: 128, bank1: 0x1F, bank2: 0x03, mode: 1, want0: 0x60, want4: 0x7F},
+		{name: "banks over ROM size are mirrored", banks: 32, bank1: 0x02, bank2: 0x01, want0: 0x00, want4: 0x02},
+		{name: "mode 1 banks over ROM size are mirrored", banks: 32, bank1: 0x02, bank2: 0x01, mode: 1, want0: 0x00, want4: 0x02},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMBC1(newBankedROM(tt.banks), NO_RAM)
+			m.Write(0x2000, tt.bank1)
+			m.Write(0x4000, tt.bank2)
+			m.Write(0x6000, tt.mode)
+			assert.Equal(t, tt.want0, m.Read(0x0000))
+			assert.Equal(t, tt.want4, m.Read(0x4000))
+		})
+	}
+}
+
+func TestMBC1_RAM(t *testing.T) {
+	tests := []struct {
+		name string
+		mode byte
+		// RAM bank written with BANK2=1
+		want int
+	}{
+		{name: "mode 0 uses bank 0", mode: 0, want: 0},
+		{name: "mode 1 uses BANK2", mode: 1, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMBC1(newBankedROM(4), RAM_32KB)
+			m.Write(0x0000, 0x0A)
+			m.Write(0x6000, tt.mode)
+			m.Write(0x4000, 0x01)
+			m.Write(0xA000, 0x42)
+			assert.Equal(t, byte(0x42), m.RAM.Buf[tt.want*0x2000])
+			assert.Equal(t, byte(0x42), m.Read(0xA000))
+
+			m.Write(0x0000, 0x00)
+			assert.Equal(t, byte(0xFF), m.Read(0xA000))
+		})
+	}
+}
+
+func TestMBC1_Registers(t *testing.T) {
+	m := NewMBC1(newBankedROM(128), RAM_32KB)
+	m.Write(0x0000, 0x0A)
+	m.Write(0x2000, 0x05)
+	m.Write(0x4000, 0x02)
+	m.Write(0x6000, 0x01)
+
+	restored := NewMBC1(newBankedROM(128), RAM_32KB)
+	for _, r := range m.Registers() {
+		restored.Write(r.Addr, r.Value)
+	}
+	assert.Equal(t, m.Read(0x0000), restored.Read(0x0000))
+	assert.Equal(t, m.Read(0x4000), restored.Read(0x4000))
+	assert.Equal(t, byte(0x45), restored.Read(0x4000))
+	assert.True(t, restored.ramEnable)
+	assert.Equal(t, byte(0x02), restored.ramBank)
+}
+
+func TestMBC1_RAMEnable(t *testing.T) {
+	tests := []struct {
+		name  string
+		addr  types.Addr
+		value byte
+		want  bool
+	}{
+		{name: "0x0A", addr: 0x0000, value: 0x0A, want: true},
+		{name: "upper bits are ignored", addr: 0x0000, value: 0xFA, want: true},
+		{name: "other values disable", addr: 0x0000, value: 0x0B, want: false},
+		{name: "any address under 0x2000", addr: 0x1FFF, value: 0x3A, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMBC1(newBankedROM(4), RAM_8KB)
+			m.Write(0x0000, 0x0A)
+			m.Write(0xA000, 0x42)
+			m.Write(tt.addr, tt.value)
+			assert.Equal(t, tt.want, m.ramEnable)
+		})
+	}
+}
+
+func TestMBC1_Mode(t *testing.T) {
+	m := NewMBC1(newBankedROM(128), NO_RAM)
+	m.Write(0x4000, 0x01)
+	// only bit 0 is used
+	m.Write(0x6000, 0xFE)
+	assert.Equal(t, byte(0x00), m.Read(0x0000))
+	m.Write(0x6000, 0xFF)
+	assert.Equal(t, byte(0x20), m.Read(0x0000))
+}
+
+func TestMBC1_NoRAM(t *testing.T) {
+	m := NewMBC1(newBankedROM(4), NO_RAM)
+	m.Write(0x0000, 0x0A)
+	m.Write(0xA000, 0x42)
+	assert.Equal(t, byte(0xFF), m.Read(0xA000))
+}
+
+func TestMBC1_RAMMirror(t *testing.T) {
+	m := NewMBC1(newBankedROM(4), RAM_8KB)
+	m.Write(0x0000, 0x0A)
+	m.Write(0x6000, 0x01)
+	m.Write(0x4000, 0x03)
+	m.Write(0xA123, 0x42)
+	m.Write(0x4000, 0x00)
+	assert.Equal(t, byte(0x42), m.Read(0xA123))
+}
+
+func TestMBC1_Multicart(t *testing.T) {
+	romData := newBankedROM(64)
+	logo := []byte{0xCE, 0xED, 0x66, 0x66}
+	copy(romData[0x0104:], logo)
+	copy(romData[0x10*0x4000+0x0104:], logo)
+
+	m := NewMBC1(romData, NO_RAM)
+	assert.True(t, m.multicart)
+	// bit 4 of BANK1 is not connected
+	m.Write(0x2000, 0x12)
+	m.Write(0x4000, 0x01)
+	assert.Equal(t, byte(0x12), m.Read(0x4000))
+	m.Write(0x6000, 0x01)
+	assert.Equal(t, byte(0x10), m.Read(0x0000))
+
+	copy(romData[0x10*0x4000+0x0104:], []byte{0, 0, 0, 0})
+	assert.False(t, NewMBC1(romData, NO_RAM).multicart)
+}
@@ -0,0 +1,118 @@
+package cartridge
+
+import (
+	"fmt"
+
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/debug"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/memory"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/state"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/types"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/util"
+)
+
+// MBC2 has built-in RAM of 512 x 4 bits
+const MBC2RAMSize = 0x200
+
+type MBC2 struct {
+	ROM *memory.ROM
+	RAM *memory.RAM
+	// max 256KB, 16 banks
+	romBank   uint8
+	ramEnable bool
+}
+
+func NewMBC2(romData []byte) *MBC2 {
+	m := &MBC2{
+		ROM:     memory.NewROM(romData),
+		RAM:     memory.NewRAM(MBC2RAMSize),
+		romBank: 1,
+	}
+
+	debug.Info("Cartridge ROM Size = %d", len(m.ROM.Buf))
+
+	return m
+}
+
+func (m *MBC2) Read(addr types.Addr) byte {
+	// @see https://gbdev.io/pandocs/MBC2.html
+	switch {
+	case addr < 0x4000:
+		return m.ROM.Read(uint32(addr))
+	case 0x4000 <= addr && addr < 0x8000:
+		offset := uint32(m.romBank)*0x4000 + uint32(addr) - 0x4000
+		return m.ROM.Read(offset % uint32(len(m.ROM.Buf)))
+	case 0xA000 <= addr && addr < 0xC000:
+		if !m.ramEnable {
+			return 0xFF
+		}
+		// only lower 4 bits are used, upper bits read as 1
+		return m.RAM.Read(m.ramAddr(addr)) | 0xF0
+	default:
+		msg := fmt.Sprintf("Non Supported addr 0x%4X for Read MBC2", addr)
+		panic(msg)
+	}
+}
+
+func (m *MBC2) Write(addr types.Addr, value byte) {
+	// @see https://gbdev.io/pandocs/MBC2.html
+	switch {
+	case addr < 0x4000:
+		// bit 8 of address selects which register is written
+		if util.Bit(util.ExtractUpper(addr), 0) == 1 {
+			m.SwitchROMBank(uint16(value & 0x0F))
+		} else {
+			m.ramEnable = value&0x0F == 0x0A
+		}
+	case 0xA000 <= addr && addr < 0xC000:
+		if m.ramEnable {
+			m.RAM.Write(m.ramAddr(addr), value&0x0F)
+		}
+	}
+}
+
+// 512 bytes are echoed across A000-BFFF
+func (m *MBC2) ramAddr(addr types.Addr) types.Addr {
+	return (addr - 0xA000) & (MBC2RAMSize - 1)
+}
+
+func (m *MBC2) SwitchROMBank(bank uint16) {
+	if bank == 0x00 {
+		bank = 0x01
+	}
+
+	m.romBank = uint8(bank)
+}
+
+// nop
+func (m *MBC2) SwitchRAMBank(bank uint8) {
+}
+
+// one byte per cell, upper 4 bits are unused
+func (m *MBC2) Dump() []byte {
+	return append([]byte(nil), m.RAM.Buf...)
+}
+
+func (m *MBC2) Restore(data []byte) {
+	for i := 0; i < len(m.RAM.Buf) && i < len(data); i++ {
+		m.RAM.Buf[i] = data[i] & 0x0F
+	}
+}
+
+func (m *MBC2) SaveState(w *state.Writer) {
+	w.Write(m.romBank)
+	w.Write(m.ramEnable)
+	m.RAM.SaveState(w)
+}
+
+func (m *MBC2) LoadState(r *state.Reader) {
+	r.Read(&m.romBank)
+	r.Read(&m.ramEnable)
+	m.RAM.LoadState(r)
+}
+
+func (m *MBC2) Registers() []RegisterWrite {
+	return []RegisterWrite{
+		{0x0000, ramEnableValue(m.ramEnable)},
+		{0x0100, m.romBank},
+	}
+}
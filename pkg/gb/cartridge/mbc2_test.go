@@ -0,0 +1,66 @@
+package cartridge
+
+import (
+	"testing"
+
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMBC2_Write(t *testing.T) {
+	type args struct {
+		addr  types.Addr
+		value byte
+	}
+	tests := []struct {
+		name      string
+		args      args
+		romBank   uint8
+		ramEnable bool
+	}{
+		{name: "RAM enable", args: args{0x0000, 0x0A}, romBank: 1, ramEnable: true},
+		{name: "RAM enable upper nibble is ignored", args: args{0x00FF, 0xFA}, romBank: 1, ramEnable: true},
+		{name: "RAM enable echo", args: args{0x3EFF, 0x0A}, romBank: 1, ramEnable: true},
+		{name: "RAM disable", args: args{0x0000, 0x00}, romBank: 1, ramEnable: false},
+		{name: "ROM bank", args: args{0x0100, 0x05}, romBank: 5, ramEnable: false},
+		{name: "ROM bank echo", args: args{0x3FFF, 0x0F}, romBank: 15, ramEnable: false},
+		{name: "ROM bank upper nibble is ignored", args: args{0x2100, 0x13}, romBank: 3, ramEnable: false},
+		{name: "ROM bank 0 is bank 1", args: args{0x2100, 0x10}, romBank: 1, ramEnable: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMBC2(newBankedROM(16))
+			m.Write(tt.args.addr, tt.args.value)
+			assert.Equal(t, tt.romBank, m.romBank)
+			assert.Equal(t, tt.ramEnable, m.ramEnable)
+			assert.Equal(t, tt.romBank, m.Read(0x4000))
+		})
+	}
+}
+
+func TestMBC2_RAM(t *testing.T) {
+	m := NewMBC2(newBankedROM(16))
+
+	t.Run("disabled", func(t *testing.T) {
+		m.Write(0xA000, 0x05)
+		assert.Equal(t, byte(0xFF), m.Read(0xA000))
+	})
+
+	m.Write(0x0000, 0x0A)
+
+	t.Run("half byte", func(t *testing.T) {
+		m.Write(0xA000, 0x5A)
+		assert.Equal(t, byte(0xFA), m.Read(0xA000))
+	})
+
+	t.Run("echo", func(t *testing.T) {
+		m.Write(0xA1FF, 0x03)
+		assert.Equal(t, byte(0xF3), m.Read(0xA1FF))
+		assert.Equal(t, byte(0xF3), m.Read(0xA3FF))
+		assert.Equal(t, byte(0xF3), m.Read(0xBFFF))
+
+		m.Write(0xBE00, 0x07)
+		assert.Equal(t, byte(0xF7), m.Read(0xA000))
+	})
+}
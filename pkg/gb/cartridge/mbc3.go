@@ -0,0 +1,181 @@
+package cartridge
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/debug"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/memory"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/state"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/types"
+)
+
+type MBC3 struct {
+	ROM *memory.ROM
+	RAM *memory.RAM
+	RTC *RTC
+	// max 2MB, 128 banks
+	romBank uint8
+	// 0x00-0x03 is RAM bank, 0x08-0x0C is RTC register
+	ramBank   uint8
+	ramEnable bool
+}
+
+func NewMBC3(romData []byte, ramSize int, hasRTC bool) *MBC3 {
+	m := &MBC3{
+		ROM:     memory.NewROM(romData),
+		romBank: 1,
+		ramBank: 0,
+	}
+
+	debug.Info("Cartridge ROM Size = %d", len(m.ROM.Buf))
+	debug.Info("Cartridge RAM Size = %d", ramSize)
+	if ramSize > 0 {
+		m.RAM = memory.NewRAM(ramSize)
+	}
+	if hasRTC {
+		m.RTC = NewRTC(time.Now)
+	}
+
+	return m
+}
+
+// SetClock replaces the time source of RTC
+func (m *MBC3) SetClock(now func() time.Time) {
+	if m.RTC == nil {
+		return
+	}
+	m.RTC.now = now
+	m.RTC.last = now()
+}
+
+func (m *MBC3) Read(addr types.Addr) byte {
+	// @see https://gbdev.io/pandocs/MBC3.html
+	switch {
+	case addr < 0x4000:
+		return m.ROM.Read(uint32(addr))
+	case 0x4000 <= addr && addr < 0x8000:
+		offset := uint32(m.romBank)*0x4000 + uint32(addr) - 0x4000
+		return m.ROM.Read(offset % uint32(len(m.ROM.Buf)))
+	case 0xA000 <= addr && addr < 0xC000:
+		if !m.ramEnable {
+			return 0xFF
+		}
+		if m.ramBank >= RTC_S {
+			if m.RTC == nil {
+				return 0xFF
+			}
+			return m.RTC.Read(m.ramBank)
+		}
+		if m.RAM == nil {
+			return 0xFF
+		}
+		return m.RAM.Read(m.ramAddr(addr))
+	default:
+		msg := fmt.Sprintf("Non Supported addr 0x%4X for Read MBC3", addr)
+		panic(msg)
+	}
+}
+
+func (m *MBC3) Write(addr types.Addr, value byte) {
+	// @see https://gbdev.io/pandocs/MBC3.html
+	switch {
+	case addr < 0x2000:
+		m.ramEnable = value&0x0F == 0x0A
+	case 0x2000 <= addr && addr < 0x4000:
+		m.SwitchROMBank(uint16(value & 0x7F))
+	case 0x4000 <= addr && addr < 0x6000:
+		m.SwitchRAMBank(value)
+	case 0x6000 <= addr && addr < 0x8000:
+		if m.RTC != nil {
+			m.RTC.Latch(value)
+		}
+	case 0xA000 <= addr && addr < 0xC000:
+		if !m.ramEnable {
+			return
+		}
+		if m.ramBank >= RTC_S {
+			if m.RTC != nil {
+				m.RTC.Write(m.ramBank, value)
+			}
+			return
+		}
+		if m.RAM != nil {
+			m.RAM.Write(m.ramAddr(addr), value)
+		}
+	}
+}
+
+// RAM smaller than 32KB is mirrored
+func (m *MBC3) ramAddr(addr types.Addr) types.Addr {
+	offset := int(m.ramBank)*0x2000 + int(addr) - 0xA000
+	return types.Addr(offset % len(m.RAM.Buf))
+}
+
+func (m *MBC3) SwitchROMBank(bank uint16) {
+	if bank == 0x00 {
+		bank = 0x01
+	}
+
+	m.romBank = uint8(bank)
+}
+
+func (m *MBC3) SwitchRAMBank(bank uint8) {
+	if bank <= 0x03 || (RTC_S <= bank && bank <= RTC_DH) {
+		m.ramBank = bank
+	}
+}
+
+// RTC is appended after RAM as other emulators do
+func (m *MBC3) Dump() []byte {
+	var data []byte
+	if m.RAM != nil {
+		data = append(data, m.RAM.Buf...)
+	}
+	if m.RTC != nil {
+		data = append(data, m.RTC.Dump()...)
+	}
+	return data
+}
+
+func (m *MBC3) Restore(data []byte) {
+	if m.RAM != nil {
+		n := copy(m.RAM.Buf, data)
+		data = data[n:]
+	}
+	if m.RTC != nil {
+		m.RTC.Restore(data)
+	}
+}
+
+func (m *MBC3) SaveState(w *state.Writer) {
+	w.Write(m.romBank)
+	w.Write(m.ramBank)
+	w.Write(m.ramEnable)
+	if m.RAM != nil {
+		m.RAM.SaveState(w)
+	}
+	if m.RTC != nil {
+		m.RTC.SaveState(w)
+	}
+}
+
+func (m *MBC3) LoadState(r *state.Reader) {
+	r.Read(&m.romBank)
+	r.Read(&m.ramBank)
+	r.Read(&m.ramEnable)
+	if m.RAM != nil {
+		m.RAM.LoadState(r)
+	}
+	if m.RTC != nil {
+		m.RTC.LoadState(r)
+	}
+}
+
+func (m *MBC3) Registers() []RegisterWrite {
+	return []RegisterWrite{
+		{0x0000, ramEnableValue(m.ramEnable)},
+		{0x2000, m.romBank},
+		{0x4000, m.ramBank},
+	}
+}
@@ -0,0 +1,144 @@
+package cartridge
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func latch(m *MBC3) {
+	m.Write(0x6000, 0x00)
+	m.Write(0x6000, 0x01)
+}
+
+func readRTC(m *MBC3, reg byte) byte {
+	m.Write(0x4000, reg)
+	return m.Read(0xA000)
+}
+
+func writeRTC(m *MBC3, reg byte, value byte) {
+	m.Write(0x4000, reg)
+	m.Write(0xA000, value)
+}
+
+func TestMBC3_SwitchROMBank(t *testing.T) {
+	tests := []struct {
+		name  string
+		value byte
+		want  byte
+	}{
+		{name: "bank 0 is bank 1", value: 0x00, want: 0x01},
+		{name: "bank 1", value: 0x01, want: 0x01},
+		{name: "bank 0x20", value: 0x20, want: 0x20},
+		{name: "bank 0x7F", value: 0x7F, want: 0x7F},
+		{name: "upper bit is ignored", value: 0x85, want: 0x05},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := setupMBC3()
+			m.Write(0x2000, tt.value)
+			assert.Equal(t, byte(0x00), m.Read(0x0000))
+			assert.Equal(t, tt.want, m.Read(0x4000))
+		})
+	}
+}
+
+func TestMBC3_RAM(t *testing.T) {
+	m, _ := setupMBC3()
+
+	for bank := byte(0); bank < 4; bank++ {
+		m.Write(0x4000, bank)
+		m.Write(0xA000, bank+0x10)
+		m.Write(0xBFFF, bank+0x20)
+	}
+	for bank := byte(0); bank < 4; bank++ {
+		m.Write(0x4000, bank)
+		assert.Equal(t, bank+0x10, m.Read(0xA000))
+		assert.Equal(t, bank+0x20, m.Read(0xBFFF))
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		m.Write(0x0000, 0x00)
+		assert.Equal(t, byte(0xFF), m.Read(0xA000))
+		m.Write(0xA000, 0x55)
+		m.Write(0x0000, 0x0A)
+		assert.Equal(t, byte(0x13), m.Read(types.Addr(0xA000)))
+	})
+}
+
+func TestMBC3_RTC(t *testing.T) {
+	t.Run("latch", func(t *testing.T) {
+		m, clock := setupMBC3()
+		clock.Advance(61 * time.Second)
+		// registers are not updated until latched
+		assert.Equal(t, byte(0), readRTC(m, RTC_S))
+
+		latch(m)
+		assert.Equal(t, byte(1), readRTC(m, RTC_S))
+		assert.Equal(t, byte(1), readRTC(m, RTC_M))
+
+		clock.Advance(10 * time.Second)
+		assert.Equal(t, byte(1), readRTC(m, RTC_S))
+		latch(m)
+		assert.Equal(t, byte(11), readRTC(m, RTC_S))
+	})
+
+	t.Run("count up", func(t *testing.T) {
+		m, clock := setupMBC3()
+		clock.Advance(300*24*time.Hour + 23*time.Hour + 59*time.Minute + 58*time.Second)
+		latch(m)
+		assert.Equal(t, byte(58), readRTC(m, RTC_S))
+		assert.Equal(t, byte(59), readRTC(m, RTC_M))
+		assert.Equal(t, byte(23), readRTC(m, RTC_H))
+		assert.Equal(t, byte(300&0xFF), readRTC(m, RTC_DL))
+		assert.Equal(t, byte(0x01), readRTC(m, RTC_DH))
+	})
+
+	t.Run("halt", func(t *testing.T) {
+		m, clock := setupMBC3()
+		writeRTC(m, RTC_DH, 0x40)
+		clock.Advance(time.Hour)
+		latch(m)
+		assert.Equal(t, byte(0), readRTC(m, RTC_S))
+		assert.Equal(t, byte(0x40), readRTC(m, RTC_DH))
+
+		writeRTC(m, RTC_S, 30)
+		writeRTC(m, RTC_DH, 0x00)
+		clock.Advance(5 * time.Second)
+		latch(m)
+		assert.Equal(t, byte(35), readRTC(m, RTC_S))
+	})
+
+	t.Run("day carry", func(t *testing.T) {
+		m, clock := setupMBC3()
+		writeRTC(m, RTC_DL, 0xFF)
+		writeRTC(m, RTC_DH, 0x01)
+		clock.Advance(24 * time.Hour)
+		latch(m)
+		assert.Equal(t, byte(0x00), readRTC(m, RTC_DL))
+		assert.Equal(t, byte(0x80), readRTC(m, RTC_DH))
+
+		// carry is kept until cleared
+		clock.Advance(24 * time.Hour)
+		latch(m)
+		assert.Equal(t, byte(0x01), readRTC(m, RTC_DL))
+		assert.Equal(t, byte(0x80), readRTC(m, RTC_DH))
+
+		writeRTC(m, RTC_DH, 0x00)
+		latch(m)
+		assert.Equal(t, byte(0x00), readRTC(m, RTC_DH))
+	})
+
+	t.Run("out of range seconds", func(t *testing.T) {
+		m, clock := setupMBC3()
+		writeRTC(m, RTC_S, 62)
+		clock.Advance(2 * time.Second)
+		latch(m)
+		// 6 bit counter overflows to 0 without minute carry
+		assert.Equal(t, byte(0), readRTC(m, RTC_S))
+		assert.Equal(t, byte(0), readRTC(m, RTC_M))
+	})
+}
@@ -0,0 +1,167 @@
+package cartridge
+
+import (
+	"fmt"
+
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/debug"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/memory"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/state"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/types"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/util"
+)
+
+type MBC5 struct {
+	ROM *memory.ROM
+	RAM *memory.RAM
+	// max 8MB, 512 banks
+	romBank uint16
+	// max 128KB, 16 banks
+	ramBank   uint8
+	ramEnable bool
+	// bit 3 of RAM bank register drives the motor on rumble carts
+	hasRumble bool
+	rumble    bool
+	onRumble  func(bool)
+}
+
+func NewMBC5(romData []byte, ramSize int, hasRumble bool) *MBC5 {
+	m := &MBC5{
+		ROM: memory.NewROM(romData),
+		// unlike MBC1, bank 0 can be selected
+		romBank:   1,
+		ramBank:   0,
+		hasRumble: hasRumble,
+	}
+
+	debug.Info("Cartridge ROM Size = %d", len(m.ROM.Buf))
+	debug.Info("Cartridge RAM Size = %d", ramSize)
+	if ramSize > 0 {
+		m.RAM = memory.NewRAM(ramSize)
+	}
+
+	return m
+}
+
+// SetRumbleHandler registers a callback called when the motor state changes
+func (m *MBC5) SetRumbleHandler(f func(bool)) {
+	m.onRumble = f
+}
+
+func (m *MBC5) Read(addr types.Addr) byte {
+	// @see https://gbdev.io/pandocs/MBC5.html
+	switch {
+	case addr < 0x4000:
+		return m.ROM.Read(uint32(addr))
+	case 0x4000 <= addr && addr < 0x8000:
+		offset := uint32(m.romBank)*0x4000 + uint32(addr) - 0x4000
+		return m.ROM.Read(offset % uint32(len(m.ROM.Buf)))
+	case 0xA000 <= addr && addr < 0xC000:
+		if !m.ramEnable || m.RAM == nil {
+			return 0xFF
+		}
+		// 128KB exceeds types.Addr, so access Buf directly
+		return m.RAM.Buf[m.ramAddr(addr)]
+	default:
+		msg := fmt.Sprintf("Non Supported addr 0x%4X for Read MBC5", addr)
+		panic(msg)
+	}
+}
+
+func (m *MBC5) Write(addr types.Addr, value byte) {
+	// @see https://gbdev.io/pandocs/MBC5.html
+	switch {
+	case addr < 0x2000:
+		m.ramEnable = value&0x0F == 0x0A
+	case 0x2000 <= addr && addr < 0x3000:
+		// lower 8 bits of ROM bank
+		m.SwitchROMBank(m.romBank&0x100 | uint16(value))
+	case 0x3000 <= addr && addr < 0x4000:
+		// 9th bit of ROM bank
+		m.SwitchROMBank(m.romBank&0xFF | uint16(value&0x01)<<8)
+	case 0x4000 <= addr && addr < 0x6000:
+		if m.hasRumble {
+			m.setRumble(util.Bit(value, 3) == 1)
+			m.SwitchRAMBank(value & 0x07)
+		} else {
+			m.SwitchRAMBank(value & 0x0F)
+		}
+	case 0xA000 <= addr && addr < 0xC000:
+		if m.ramEnable && m.RAM != nil {
+			m.RAM.Buf[m.ramAddr(addr)] = value
+		}
+	}
+}
+
+// RAM smaller than 128KB is mirrored
+func (m *MBC5) ramAddr(addr types.Addr) uint32 {
+	offset := uint32(m.ramBank)*0x2000 + uint32(addr) - 0xA000
+	return offset % uint32(len(m.RAM.Buf))
+}
+
+func (m *MBC5) setRumble(on bool) {
+	if m.rumble == on {
+		return
+	}
+	m.rumble = on
+	if m.onRumble != nil {
+		m.onRumble(on)
+	}
+}
+
+func (m *MBC5) SwitchROMBank(bank uint16) {
+	m.romBank = bank & 0x1FF
+}
+
+func (m *MBC5) SwitchRAMBank(bank uint8) {
+	m.ramBank = bank
+}
+
+func (m *MBC5) Dump() []byte {
+	if m.RAM == nil {
+		return nil
+	}
+	return append([]byte(nil), m.RAM.Buf...)
+}
+
+func (m *MBC5) Restore(data []byte) {
+	if m.RAM == nil {
+		return
+	}
+	copy(m.RAM.Buf, data)
+}
+
+func (m *MBC5) SaveState(w *state.Writer) {
+	w.Write(m.romBank)
+	w.Write(m.ramBank)
+	w.Write(m.ramEnable)
+	w.Write(m.rumble)
+	if m.RAM != nil {
+		m.RAM.SaveState(w)
+	}
+}
+
+func (m *MBC5) LoadState(r *state.Reader) {
+	r.Read(&m.romBank)
+	r.Read(&m.ramBank)
+	r.Read(&m.ramEnable)
+	var rumble bool
+	r.Read(&rumble)
+	m.setRumble(rumble)
+	if m.RAM != nil {
+		m.RAM.LoadState(r)
+	}
+}
+
+func (m *MBC5) Registers() []RegisterWrite {
+	bank := m.ramBank
+	if m.hasRumble && m.rumble {
+		bank |= 0x08
+	}
+
+	return []RegisterWrite{
+		{0x0000, ramEnableValue(m.ramEnable)},
+		{0x2000, byte(m.romBank)},
+		{0x3000, byte(m.romBank >> 8)},
+		{0x4000, bank},
+	}
+}
@@ -0,0 +1,75 @@
+package cartridge
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMBC5_SwitchROMBank(t *testing.T) {
+	tests := []struct {
+		name string
+		low  byte
+		high byte
+		want uint16
+	}{
+		{name: "bank 0", low: 0x00, high: 0x00, want: 0x000},
+		{name: "bank 1", low: 0x01, high: 0x00, want: 0x001},
+		{name: "bank 0xFF", low: 0xFF, high: 0x00, want: 0x0FF},
+		{name: "bank 0x100", low: 0x00, high: 0x01, want: 0x100},
+		{name: "bank 0x1FF", low: 0xFF, high: 0x01, want: 0x1FF},
+		{name: "upper bits of high are ignored", low: 0x23, high: 0xFE, want: 0x023},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMBC5(newBankedROM(512), NO_RAM, false)
+			m.Write(0x2000, tt.low)
+			m.Write(0x3000, tt.high)
+			assert.Equal(t, byte(tt.want), m.Read(0x4000))
+			assert.Equal(t, byte(tt.want>>8), m.Read(0x4001))
+		})
+	}
+}
+
+func TestMBC5_RAM(t *testing.T) {
+	m := NewMBC5(newBankedROM(4), RAM_128KB, false)
+	m.Write(0x0000, 0x0A)
+
+	for bank := byte(0); bank < 16; bank++ {
+		m.Write(0x4000, bank)
+		m.Write(0xA000, bank)
+		m.Write(0xBFFF, ^bank)
+	}
+	for bank := byte(0); bank < 16; bank++ {
+		m.Write(0x4000, bank)
+		assert.Equal(t, bank, m.Read(0xA000))
+		assert.Equal(t, ^bank, m.Read(0xBFFF))
+	}
+
+	m.Write(0x0000, 0x00)
+	assert.Equal(t, byte(0xFF), m.Read(0xA000))
+}
+
+func TestMBC5_Rumble(t *testing.T) {
+	romData := newBankedROM(4)
+	romData[0x0147] = 0x1E
+	romData[0x0149] = 0x03
+	c := New(romData)
+
+	var got []bool
+	c.OnRumble(func(on bool) {
+		got = append(got, on)
+	})
+
+	c.WriteByte(0x0000, 0x0A)
+	c.WriteByte(0x4000, 0x09)
+	// same state doesn't notify
+	c.WriteByte(0x4000, 0x0B)
+	c.WriteByte(0xA000, 0x42)
+	c.WriteByte(0x4000, 0x03)
+
+	assert.Equal(t, []bool{true, false}, got)
+	// motor bit isn't a part of RAM bank
+	assert.Equal(t, byte(0x42), c.ReadByte(0xA000))
+}
@@ -0,0 +1,229 @@
+package cartridge
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/state"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/util"
+)
+
+// RTC Register select
+// @see https://gbdev.io/pandocs/MBC3.html#the-clock-counter-registers
+const (
+	RTC_S  byte = 0x08
+	RTC_M  byte = 0x09
+	RTC_H  byte = 0x0A
+	RTC_DL byte = 0x0B
+	RTC_DH byte = 0x0C
+)
+
+const secondsPerDay = 24 * 60 * 60
+
+// RTC is the Real Time Clock of MBC3
+// now is a time source, which can be replaced for testing
+type RTC struct {
+	now func() time.Time
+	// when the counter was synced last time
+	last time.Time
+
+	S, M, H, DL, DH byte
+	latched         [5]byte
+	// last value written to latch register
+	latch byte
+}
+
+func NewRTC(now func() time.Time) *RTC {
+	return &RTC{
+		now:   now,
+		last:  now(),
+		latch: 0xFF,
+	}
+}
+
+// Bit 6 of DH
+func (r *RTC) halted() bool {
+	return util.Bit(r.DH, 6) == 1
+}
+
+func (r *RTC) days() uint16 {
+	return uint16(util.Bit(r.DH, 0))<<8 | uint16(r.DL)
+}
+
+func (r *RTC) setDays(d uint16) {
+	r.DL = byte(d)
+	r.DH = util.SetBit(r.DH, 0, d&0x100 != 0)
+}
+
+// sync advances counter by the elapsed time from last sync
+func (r *RTC) sync() {
+	now := r.now()
+	if r.halted() {
+		r.last = now
+		return
+	}
+
+	secs := int64(now.Sub(r.last) / time.Second)
+	if secs <= 0 {
+		return
+	}
+	// keep sub second fraction for next sync
+	r.last = r.last.Add(time.Duration(secs) * time.Second)
+	r.advance(secs)
+}
+
+func (r *RTC) advance(secs int64) {
+	// registers can be written out of range values,
+	// so count up one by one until they are back in range
+	for secs > 0 && (r.S >= 60 || r.M >= 60 || r.H >= 24) {
+		r.tick()
+		secs--
+	}
+
+	total := int64(r.S) + int64(r.M)*60 + int64(r.H)*3600 + int64(r.days())*secondsPerDay + secs
+
+	days := total / secondsPerDay
+	if days > 0x1FF {
+		// Day Counter Carry Bit stays set until the program resets it
+		r.DH = util.SetBit(r.DH, 7, true)
+		days &= 0x1FF
+	}
+	r.setDays(uint16(days))
+
+	total %= secondsPerDay
+	r.H = byte(total / 3600)
+	r.M = byte(total % 3600 / 60)
+	r.S = byte(total % 60)
+}
+
+// tick counts up one second
+func (r *RTC) tick() {
+	r.S = (r.S + 1) & 0x3F
+	if r.S != 60 {
+		return
+	}
+	r.S = 0
+
+	r.M = (r.M + 1) & 0x3F
+	if r.M != 60 {
+		return
+	}
+	r.M = 0
+
+	r.H = (r.H + 1) & 0x1F
+	if r.H != 24 {
+		return
+	}
+	r.H = 0
+
+	d := r.days() + 1
+	if d > 0x1FF {
+		r.DH = util.SetBit(r.DH, 7, true)
+		d = 0
+	}
+	r.setDays(d)
+}
+
+// Writing 00h and then 01h latches the current time into the RTC registers
+func (r *RTC) Latch(value byte) {
+	if r.latch == 0x00 && value == 0x01 {
+		r.sync()
+		r.latched = [5]byte{r.S, r.M, r.H, r.DL, r.DH}
+	}
+	r.latch = value
+}
+
+func (r *RTC) Read(reg byte) byte {
+	switch reg {
+	case RTC_S:
+		return r.latched[0] & 0x3F
+	case RTC_M:
+		return r.latched[1] & 0x3F
+	case RTC_H:
+		return r.latched[2] & 0x1F
+	case RTC_DL:
+		return r.latched[3]
+	case RTC_DH:
+		return r.latched[4] & 0xC1
+	default:
+		return 0xFF
+	}
+}
+
+func (r *RTC) Write(reg byte, value byte) {
+	r.sync()
+
+	switch reg {
+	case RTC_S:
+		r.S = value & 0x3F
+		// writing seconds resets the internal sub second counter
+		r.last = r.now()
+	case RTC_M:
+		r.M = value & 0x3F
+	case RTC_H:
+		r.H = value & 0x1F
+	case RTC_DL:
+		r.DL = value
+	case RTC_DH:
+		r.DH = value & 0xC1
+	}
+}
+
+// RTCSaveSize is the size of RTC data appended to .sav
+// 5 registers, 5 latched registers as 32 bit and 64 bit UNIX time
+// @see https://bgb.bircd.org/rtcsave.html
+const RTCSaveSize = 48
+
+func (r *RTC) Dump() []byte {
+	r.sync()
+
+	data := make([]byte, RTCSaveSize)
+	regs := [5]byte{r.S, r.M, r.H, r.DL, r.DH}
+	for i := 0; i < 5; i++ {
+		binary.LittleEndian.PutUint32(data[i*4:], uint32(regs[i]))
+		binary.LittleEndian.PutUint32(data[20+i*4:], uint32(r.latched[i]))
+	}
+	binary.LittleEndian.PutUint64(data[40:], uint64(r.last.Unix()))
+
+	return data
+}
+
+// Restore also accepts 44 bytes format, which has 32 bit UNIX time
+func (r *RTC) Restore(data []byte) {
+	if len(data) < RTCSaveSize-4 {
+		return
+	}
+
+	regs := [5]*byte{&r.S, &r.M, &r.H, &r.DL, &r.DH}
+	for i := 0; i < 5; i++ {
+		*regs[i] = byte(binary.LittleEndian.Uint32(data[i*4:]))
+		r.latched[i] = byte(binary.LittleEndian.Uint32(data[20+i*4:]))
+	}
+
+	var unix int64
+	if len(data) >= RTCSaveSize {
+		unix = int64(binary.LittleEndian.Uint64(data[40:]))
+	} else {
+		unix = int64(binary.LittleEndian.Uint32(data[40:]))
+	}
+
+	// count up the time passed while the emulator was not running
+	r.last = time.Unix(unix, 0)
+	r.sync()
+}
+
+// the time of last sync is saved,
+// so the clock counts up the time while the state was not loaded
+func (r *RTC) SaveState(w *state.Writer) {
+	w.Write(r.Dump())
+	w.Write(r.latch)
+}
+
+func (r *RTC) LoadState(sr *state.Reader) {
+	data := make([]byte, RTCSaveSize)
+	sr.Read(data)
+	sr.Read(&r.latch)
+	if sr.Err() == nil {
+		r.Restore(data)
+	}
+}
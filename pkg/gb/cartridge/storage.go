@@ -0,0 +1,69 @@
+package cartridge
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Storage persists battery backed external RAM
+// The data is raw RAM dump, as .sav files of other emulators
+type Storage interface {
+	// Load returns nil without error when nothing has been saved yet
+	Load() ([]byte, error)
+	Save(data []byte) error
+}
+
+// FileStorage stores RAM to a file
+type FileStorage struct {
+	Path string
+}
+
+func NewFileStorage(path string) *FileStorage {
+	return &FileStorage{
+		Path: path,
+	}
+}
+
+// SavPath returns .sav file path next to the ROM
+// ex: roms/game.gb -> roms/game.sav
+func SavPath(romPath string) string {
+	return strings.TrimSuffix(romPath, filepath.Ext(romPath)) + ".sav"
+}
+
+func (s *FileStorage) Load() ([]byte, error) {
+	data, err := os.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	return data, err
+}
+
+func (s *FileStorage) Save(data []byte) error {
+	// write to temporary file first not to break the save on crash
+	tmp := s.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, s.Path)
+}
+
+// MemoryStorage keeps RAM on memory, mainly for testing
+type MemoryStorage struct {
+	Data []byte
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{}
+}
+
+func (s *MemoryStorage) Load() ([]byte, error) {
+	return s.Data, nil
+}
+
+func (s *MemoryStorage) Save(data []byte) error {
+	s.Data = append([]byte(nil), data...)
+	return nil
+}
@@ -0,0 +1,106 @@
+package cartridge
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Teshima-Tatsuya/GoBoy/test/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCartridge_Storage(t *testing.T) {
+	t.Run("load and flush", func(t *testing.T) {
+		// MBC1+RAM+BATTERY, 8KB
+		c := newCartridge(0x03, 0x02)
+		s := NewMemoryStorage()
+		s.Data = make([]byte, RAM_8KB)
+		s.Data[0] = 0x12
+
+		assert.NoError(t, c.SetStorage(s))
+		c.WriteByte(0x0000, 0x0A)
+		assert.Equal(t, byte(0x12), c.ReadByte(0xA000))
+
+		c.WriteByte(0xA001, 0x34)
+		assert.NoError(t, c.Flush())
+		assert.Equal(t, byte(0x12), s.Data[0])
+		assert.Equal(t, byte(0x34), s.Data[1])
+	})
+
+	t.Run("nothing saved yet", func(t *testing.T) {
+		c := newCartridge(0x1B, 0x03)
+		s := NewMemoryStorage()
+
+		assert.NoError(t, c.SetStorage(s))
+		// not changed from load
+		assert.NoError(t, c.Flush())
+		assert.Nil(t, s.Data)
+
+		c.WriteByte(0x0000, 0x0A)
+		c.WriteByte(0xA000, 0x56)
+		assert.NoError(t, c.Flush())
+		assert.Equal(t, RAM_32KB, len(s.Data))
+		assert.Equal(t, byte(0x56), s.Data[0])
+	})
+
+	t.Run("no battery", func(t *testing.T) {
+		// MBC1+RAM
+		c := newCartridge(0x02, 0x02)
+		s := NewMemoryStorage()
+		s.Data = []byte{0x12}
+
+		assert.NoError(t, c.SetStorage(s))
+		c.WriteByte(0x0000, 0x0A)
+		assert.Equal(t, byte(0x00), c.ReadByte(0xA000))
+		c.WriteByte(0xA000, 0x34)
+		assert.NoError(t, c.Flush())
+		assert.Equal(t, []byte{0x12}, s.Data)
+	})
+
+	t.Run("MBC3 RTC", func(t *testing.T) {
+		clock := mock.NewMockClock(time.Unix(1000, 0))
+		c := newCartridge(0x10, 0x02)
+		c.MBC.(*MBC3).SetClock(clock.Now)
+		s := NewMemoryStorage()
+		assert.NoError(t, c.SetStorage(s))
+
+		clock.Advance(90 * time.Second)
+		c.WriteByte(0x0000, 0x0A)
+		c.WriteByte(0xA000, 0x78)
+		assert.NoError(t, c.Flush())
+		assert.Equal(t, RAM_8KB+RTCSaveSize, len(s.Data))
+
+		// load 1 hour later
+		c = newCartridge(0x10, 0x02)
+		clock.Advance(time.Hour)
+		c.MBC.(*MBC3).SetClock(clock.Now)
+		assert.NoError(t, c.SetStorage(s))
+
+		m := c.MBC.(*MBC3)
+		m.Write(0x0000, 0x0A)
+		assert.Equal(t, byte(0x78), m.Read(0xA000))
+		latch(m)
+		assert.Equal(t, byte(30), readRTC(m, RTC_S))
+		assert.Equal(t, byte(1), readRTC(m, RTC_M))
+		assert.Equal(t, byte(1), readRTC(m, RTC_H))
+	})
+}
+
+func TestFileStorage(t *testing.T) {
+	assert.Equal(t, "roms/game.sav", SavPath("roms/game.gb"))
+	assert.Equal(t, "game.sav", SavPath("game.gbc"))
+
+	s := NewFileStorage(filepath.Join(t.TempDir(), "game.sav"))
+	data, err := s.Load()
+	assert.NoError(t, err)
+	assert.Nil(t, data)
+
+	assert.NoError(t, s.Save([]byte{0x01, 0x02}))
+	data, err = s.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x01, 0x02}, data)
+
+	_, err = os.Stat(s.Path + ".tmp")
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,100 @@
+package gb
+
+import (
+	"testing"
+
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/cpu"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGB_CGB(t *testing.T) {
+	t.Run("registers after boot", func(t *testing.T) {
+		assert.Equal(t, byte(0x11), setupROM(0x80).Registers().R[cpu.A])
+		assert.Equal(t, byte(0x11), setupROM(0xC0).Registers().R[cpu.A])
+		assert.Equal(t, byte(0x01), setupROM(0x00).Registers().R[cpu.A])
+	})
+
+	t.Run("VRAM bank", func(t *testing.T) {
+		gb := setupROM(0x80)
+		assert.Equal(t, byte(0xFE), gb.ReadByte(0xFF4F))
+		gb.bus.WriteByte(0x8000, 0x12)
+		gb.bus.WriteByte(0xFF4F, 0xFF)
+		assert.Equal(t, byte(0xFF), gb.ReadByte(0xFF4F))
+		assert.Equal(t, byte(0x00), gb.ReadByte(0x8000))
+		gb.bus.WriteByte(0x9FFF, 0x34)
+
+		gb.bus.WriteByte(0xFF4F, 0x00)
+		assert.Equal(t, byte(0x12), gb.ReadByte(0x8000))
+		assert.Equal(t, byte(0x00), gb.ReadByte(0x9FFF))
+		assert.Equal(t, byte(0x34), gb.bus.VRAM.Buf[0x3FFF])
+	})
+
+	t.Run("WRAM bank", func(t *testing.T) {
+		gb := setupROM(0x80)
+		assert.Equal(t, byte(0xF9), gb.ReadByte(0xFF70))
+		for bank := byte(1); bank < 8; bank++ {
+			gb.bus.WriteByte(0xFF70, bank)
+			gb.bus.WriteByte(0xD000, bank)
+		}
+		for bank := byte(1); bank < 8; bank++ {
+			gb.bus.WriteByte(0xFF70, bank)
+			assert.Equal(t, bank, gb.ReadByte(0xD000))
+		}
+
+		// bank 0 selects bank 1
+		gb.bus.WriteByte(0xFF70, 0xF8)
+		assert.Equal(t, byte(0xF9), gb.ReadByte(0xFF70))
+		assert.Equal(t, byte(1), gb.ReadByte(0xD000))
+		// bank 0 is fixed
+		gb.bus.WriteByte(0xC000, 0x56)
+		gb.bus.WriteByte(0xFF70, 2)
+		assert.Equal(t, byte(0x56), gb.ReadByte(0xC000))
+	})
+
+	t.Run("registers are not mapped in DMG mode", func(t *testing.T) {
+		gb := setupROM(0x00)
+		for _, addr := range []types.Addr{0xFF4F, 0xFF51, 0xFF55, 0xFF68, 0xFF69, 0xFF6A, 0xFF6B, 0xFF70} {
+			gb.bus.WriteByte(addr, 0x01)
+			assert.Equal(t, byte(0xFF), gb.ReadByte(addr), "0x%04X", addr)
+		}
+		gb.bus.WriteByte(0xD000, 0x78)
+		assert.Equal(t, byte(0x78), gb.ReadByte(0xD000))
+	})
+
+	t.Run("palette registers", func(t *testing.T) {
+		gb := setupROM(0x80)
+		gb.bus.WriteByte(0xFF6A, 0x81)
+		gb.bus.WriteByte(0xFF6B, 0x7C)
+		assert.Equal(t, byte(0xC2), gb.ReadByte(0xFF6A))
+		gb.bus.WriteByte(0xFF6A, 0x01)
+		assert.Equal(t, byte(0x7C), gb.ReadByte(0xFF6B))
+	})
+
+	t.Run("double speed", func(t *testing.T) {
+		// STOP, JR -2
+		gb := setupROM(0x80, 0x10, 0x00, 0x18, 0xFE)
+		assert.Equal(t, byte(0x7E), gb.ReadByte(0xFF4D))
+		gb.bus.WriteByte(0xFF4D, 0x01)
+		assert.Equal(t, byte(0x7F), gb.ReadByte(0xFF4D))
+
+		gb.Step()
+		assert.Equal(t, byte(0xFE), gb.ReadByte(0xFF4D))
+		assert.True(t, gb.bus.DoubleSpeed())
+
+		// DIV increments every 64 M-cycles, and CPU runs 35112 M-cycles in a frame
+		div := gb.ReadByte(0xFF04)
+		gb.Step()
+		// 548 wraps around
+		assert.InDelta(t, 35112/64%0x100, gb.ReadByte(0xFF04)-div, 1)
+	})
+
+	t.Run("STOP without arming", func(t *testing.T) {
+		gb := setupROM(0x80, 0x10, 0x00, 0x18, 0xFE)
+		gb.Step()
+		assert.Equal(t, byte(0x7E), gb.ReadByte(0xFF4D))
+		assert.False(t, gb.bus.DoubleSpeed())
+		// enters low power mode instead
+		assert.True(t, gb.cpu.Stop)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/debug"
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/interrupt"
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/interfaces"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/state"
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/types"
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/util"
 )
@@ -13,6 +14,20 @@ type CPU struct {
 	Bus  interfaces.Bus
 	IRQ  *interrupt.IRQ
 	Halt bool
+	// low power mode by STOP, woken up by joypad
+	Stop bool
+
+	// IME is set after the instruction following EI
+	eiDelay bool
+	// HALT bug, the next byte is read twice
+	haltBug bool
+
+	// called before the opcode is executed, e.g. LD B,B of mooneye test ROMs
+	breakpoint   byte
+	onBreakpoint func()
+
+	// called by STOP, returns true when speed is switched in CGB mode
+	switchSpeed func() bool
 }
 
 func New(bus interfaces.Bus, irq *interrupt.IRQ) *CPU {
@@ -27,7 +42,19 @@ func New(bus interfaces.Bus, irq *interrupt.IRQ) *CPU {
 	return c
 }
 
+// SetCGBMode sets registers to the values after boot ROM of CGB
+func (c *CPU) SetCGBMode() {
+	c.Reg.resetCGB()
+}
+
 func (c *CPU) Step() uint {
+	if c.Stop {
+		if c.joypadSelected() {
+			c.Stop = false
+		}
+		return 1
+	}
+
 	if c.Halt {
 		if c.IRQ.Has() {
 			c.Halt = false
@@ -36,7 +63,11 @@ func (c *CPU) Step() uint {
 	}
 
 	if c.interrupt() {
-		return 1
+		return 5
+	}
+	if c.eiDelay {
+		c.eiDelay = false
+		c.IRQ.Enable()
 	}
 	opcode := c.fetch()
 
@@ -47,6 +78,9 @@ func (c *CPU) Step() uint {
 		op = cbOpCodes[opcode]
 	} else {
 		op = opCodes[opcode]
+		if c.onBreakpoint != nil && opcode == c.breakpoint {
+			c.onBreakpoint()
+		}
 	}
 
 	// log.Info(fmt.Sprintf("PC 0x%04X data 0x%02x%02x", c.Reg.PC-1, c.Bus.ReadByte(c.Reg.PC), c.Bus.ReadByte(c.Reg.PC+1)))
@@ -60,8 +94,29 @@ func (c *CPU) Step() uint {
 	return uint(op.Cycles)
 }
 
+// SetBreakpoint sets software breakpoint, nil hook removes it
+func (c *CPU) SetBreakpoint(opcode byte, hook func()) {
+	c.breakpoint = opcode
+	c.onBreakpoint = hook
+}
+
+// joypadSelected reports any of P10-P13 is low, by buttons pressed in the selected group
+func (c *CPU) joypadSelected() bool {
+	return c.Bus.ReadByte(0xFF00)&0x0F != 0x0F
+}
+
+// SetSpeedSwitch sets the function STOP switches CPU speed with
+func (c *CPU) SetSpeedSwitch(switchSpeed func() bool) {
+	c.switchSpeed = switchSpeed
+}
+
 func (c *CPU) fetch() byte {
 	d := c.Bus.ReadByte(c.Reg.PC)
+	if c.haltBug {
+		// PC fails to be incremented
+		c.haltBug = false
+		return d
+	}
 	c.Reg.PC++
 	return d
 }
@@ -104,15 +159,47 @@ func (c *CPU) getRegMem(r16 int) byte {
 	return d
 }
 
+// interrupt dispatches the interrupt in 5 M-cycles
+// The vector is decided after the upper byte of PC is pushed, so the push overwriting IE can cancel it
+// @see https://gbdev.io/pandocs/Interrupts.html#interrupt-handling
 func (c *CPU) interrupt() bool {
 	if !c.IRQ.Enabled() || !c.IRQ.Has() {
 		return false
 	}
 
+	// EI pending from the previous instruction must not enable IME in the handler
+	c.IRQ.Disable()
+	c.eiDelay = false
+	c.push(util.ExtractUpper(c.Reg.PC))
+	if !c.IRQ.Has() {
+		// cancelled, PC is set to 0x0000
+		c.push(util.ExtractLower(c.Reg.PC))
+		c.Reg.PC = 0x0000
+		return true
+	}
 	addr := c.IRQ.InterruptAddr()
-	c.pushPC()
+	c.push(util.ExtractLower(c.Reg.PC))
 	c.Reg.PC = addr
-	c.IRQ.Disable()
 
 	return true
 }
+
+func (c *CPU) SaveState(w *state.Writer) {
+	w.Write(c.Reg.R[:])
+	w.Write(c.Reg.SP)
+	w.Write(c.Reg.PC)
+	w.Write(c.Halt)
+	w.Write(c.Stop)
+	w.Write(c.eiDelay)
+	w.Write(c.haltBug)
+}
+
+func (c *CPU) LoadState(r *state.Reader) {
+	r.Read(c.Reg.R[:])
+	r.Read(&c.Reg.SP)
+	r.Read(&c.Reg.PC)
+	r.Read(&c.Halt)
+	r.Read(&c.Stop)
+	r.Read(&c.eiDelay)
+	r.Read(&c.haltBug)
+}
@@ -5,7 +5,6 @@ import (
 
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/types"
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/util"
-	"github.com/apex/log"
 )
 
 type OpCode struct {
@@ -34,7 +33,7 @@ var opCodes = []*OpCode{
 	{0x0D, "DEC C", C, 0, 0, 1, decr},
 	{0x0E, "LD C,d8", C, 0, 1, 2, ldrd},
 	{0x0F, "RRCA", 0, 0, 0, 1, rrca},
-	{0x10, "STOP 0", 0, 0, 0, 1, stop},
+	{0x10, "STOP 0", 0, 0, 1, 1, stop},
 	{0x11, "LD DE,d16", DE, 0, 2, 3, ldr16d16},
 	{0x12, "LD (DE),A", DE, A, 0, 2, ldm16r},
 	{0x13, "INC DE", DE, 0, 0, 2, incr16},
@@ -833,8 +832,35 @@ func daa(c *CPU, _ int, _ int) {
 	}
 }
 
+// STOP enters low power mode until joypad is pressed, or switches CPU speed when it is armed by KEY1
+// The second byte is skipped only when no interrupt is pending
+// @see https://gbdev.io/pandocs/Reducing_Power_Consumption.html#using-the-stop-instruction
 func stop(c *CPU, _ int, _ int) {
-	log.Debug("TODO: implement")
+	pending := c.IRQ.Has()
+
+	// a button is already held, so joypad can't wake it up
+	if c.joypadSelected() {
+		if !pending {
+			c.fetch()
+			c.Halt = true
+		}
+		return
+	}
+
+	// DIV is reset here for both the speed switch and low power mode
+	// pause of the speed switch, and glitch by an interrupt pending with IME=1 are not emulated
+	c.Bus.WriteByte(0xFF04, 0)
+	if c.switchSpeed != nil && c.switchSpeed() {
+		if !pending {
+			c.fetch()
+		}
+		return
+	}
+
+	if !pending {
+		c.fetch()
+	}
+	c.Stop = true
 }
 
 // desable interrupt
@@ -842,12 +868,18 @@ func di(c *CPU, _ int, _ int) {
 	c.IRQ.Disable()
 }
 
-// enable interrupt
+// enable interrupt after the next instruction
 func ei(c *CPU, _ int, _ int) {
-	c.IRQ.Enable()
+	c.eiDelay = true
 }
 
+// HALT is not entered when IME=0 and an interrupt is pending, and the next byte is read twice instead
+// @see https://gbdev.io/pandocs/halt.html#halt-bug
 func halt(c *CPU, _ int, _ int) {
+	if !c.IRQ.Enabled() && c.IRQ.Has() {
+		c.haltBug = true
+		return
+	}
 	c.Halt = true
 }
 
@@ -57,6 +57,20 @@ func (r *Register) reset() {
 	r.SP = 0xfffe
 }
 
+// registers after boot ROM of CGB, A=0x11 tells games they run on CGB
+// @see https://gbdev.io/pandocs/Power_Up_Sequence.html#cpu-registers
+func (r *Register) resetCGB() {
+	r.reset()
+	r.R[A] = 0x11
+	r.R[F] = 0x80
+	r.R[B] = 0x00
+	r.R[C] = 0x00
+	r.R[D] = 0xFF
+	r.R[E] = 0x56
+	r.R[H] = 0x00
+	r.R[L] = 0x0D
+}
+
 func (r *Register) R16(i int) types.Addr {
 	switch i {
 	case AF:
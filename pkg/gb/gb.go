@@ -1,10 +1,9 @@
 package gb
 
 import (
+	"crypto/sha1"
 	"image"
-	"time"
 
-	"github.com/Teshima-Tatsuya/GoBoy/pkg/emulator/joypad"
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/apu"
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/bus"
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/cartridge"
@@ -15,8 +14,15 @@ import (
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/pad"
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/serial"
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/timer"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/types"
 )
 
+// InputProvider is implemented by frontends to feed buttons to the GB
+// Buttons is called once per frame and returns all buttons held now
+type InputProvider interface {
+	Buttons() pad.Button
+}
+
 type GB struct {
 	Cartridge *cartridge.Cartridge
 
@@ -24,8 +30,13 @@ type GB struct {
 	gpu *gpu.GPU
 	apu *apu.APU
 	pad *pad.Pad
+	bus *bus.Bus
+	irq *interrupt.IRQ
 
-	timer *timer.Timer
+	timer  *timer.Timer
+	serial *serial.Serial
+
+	input InputProvider
 
 	currentCycle uint
 }
@@ -33,9 +44,10 @@ type GB struct {
 func NewGB(romData []byte) *GB {
 	cart := cartridge.New(romData)
 
-	vram := memory.NewRAM(0x2000)
+	// VRAM and WRAM2 have all banks of CGB, only the first bank is used in DMG mode
+	vram := memory.NewRAM(2 * gpu.VRAMBankSize)
 	wram := memory.NewRAM(0x2000)
-	wram2 := memory.NewRAM(0x2000)
+	wram2 := memory.NewRAM(7 * 0x1000)
 	hram := memory.NewRAM(0x0080)
 	gpu := gpu.New()
 	apu := apu.NewAPU()
@@ -47,7 +59,16 @@ func NewGB(romData []byte) *GB {
 
 	cpu := cpu.New(bus, irq)
 	timer.SetRequestIRQ(irq.Request)
+	serial.SetRequestIRQ(irq.Request)
 	gpu.Init(bus, irq.Request)
+	// CGB mode for games which support CGB
+	if cart.CGBFlag {
+		bus.SetCGBMode()
+		gpu.SetCGBMode(vram)
+		cpu.SetCGBMode()
+		serial.SetCGBMode()
+		cpu.SetSpeedSwitch(bus.SwitchSpeed)
+	}
 
 	gb := &GB{
 		Cartridge:    cart,
@@ -55,7 +76,10 @@ func NewGB(romData []byte) *GB {
 		gpu:          gpu,
 		apu:          apu,
 		pad:          pad,
+		bus:          bus,
+		irq:          irq,
 		timer:        timer,
+		serial:       serial,
 		currentCycle: 0,
 	}
 
@@ -63,34 +87,95 @@ func NewGB(romData []byte) *GB {
 }
 
 func (gb *GB) Step() {
-	time.Sleep(16 * time.Millisecond)
+	if gb.input != nil {
+		gb.pad.Set(gb.input.Buttons())
+	}
 	for {
 
 		var cycle uint
 		if gb.gpu.IsDmaStarted() {
 			gb.gpu.TransferOAM()
 			cycle = 162
+		} else if c := gb.gpu.TransferHDMA(); c > 0 {
+			// CPU is stopped while VRAM DMA copies blocks
+			// VRAM DMA takes the same time in double speed mode
+			cycle = c
+			if gb.bus.DoubleSpeed() {
+				cycle *= 2
+			}
 		} else {
+			stopped := gb.cpu.Stop
 			cycle = gb.cpu.Step()
+			if gb.cpu.Stop && !stopped {
+				gb.gpu.Blank()
+			}
 		}
-		gb.gpu.Step(cycle * 4)
 
-		gb.currentCycle += cycle * 4
+		// timer and serial are clocked by CPU, PPU is not affected by double speed mode
+		dots := cycle * 4
+		if gb.bus.DoubleSpeed() {
+			dots = cycle * 2
+		}
+		gb.currentCycle += dots
 
-		gb.timer.Tick(cycle)
+		// all clocks are stopped in STOP mode, but frames are still returned to read joypad
+		if !gb.cpu.Stop {
+			gb.gpu.Step(dots)
+			gb.timer.Tick(cycle)
+			gb.serial.Tick(cycle)
+		}
 
 		if gb.currentCycle >= 70224 {
-			var button pad.Button
-			button = joypad.Press()
-			gb.pad.Press(button)
-			button = joypad.Release()
-			gb.pad.Release(button)
 			gb.currentCycle -= 70224
 			return
 		}
 	}
 }
 
+// SetInputProvider sets the source of buttons, nil disconnects it
+func (gb *GB) SetInputProvider(input InputProvider) {
+	gb.input = input
+}
+
+func (gb *GB) Press(button pad.Button) {
+	gb.pad.Press(button)
+}
+
+func (gb *GB) Release(button pad.Button) {
+	gb.pad.Release(button)
+}
+
+// SetSerialOutput sets the function called with every byte sent from serial port
+func (gb *GB) SetSerialOutput(output func(byte)) {
+	gb.serial.SetOutput(output)
+}
+
+// SetRenderer selects the renderer of GPU, ScanlineRenderer by default
+func (gb *GB) SetRenderer(r gpu.Renderer) {
+	gb.gpu.SetRenderer(r)
+}
+
+// SetBreakpoint calls hook when the CPU executes opcode, nil hook removes it
+func (gb *GB) SetBreakpoint(opcode byte, hook func()) {
+	gb.cpu.SetBreakpoint(opcode, hook)
+}
+
+// Registers returns copy of CPU registers
+func (gb *GB) Registers() cpu.Register {
+	return gb.cpu.Reg
+}
+
+// ReadByte reads memory as seen from CPU
+func (gb *GB) ReadByte(addr types.Addr) byte {
+	return gb.bus.ReadByte(addr)
+}
+
+// FrameHash returns hash of the current screen, for quick comparison of frames
+func (gb *GB) FrameHash() [sha1.Size]byte {
+	screen, _ := gb.Display()
+	return sha1.Sum(screen.Pix)
+}
+
 func (gb *GB) Display() (*image.RGBA, *image.RGBA) {
 	return gb.gpu.Display()
 }
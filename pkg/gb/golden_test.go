@@ -0,0 +1,110 @@
+package gb
+
+import (
+	"flag"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden images in test/expected")
+
+const (
+	actualPath   = "../../test/actual/"
+	expectedPath = "../../test/expected/"
+	diffPath     = "../../test/diff/"
+)
+
+func writePNG(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func readPNG(path string) (*image.RGBA, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, err := png.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+	rgba := image.NewRGBA(img.Bounds())
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	return rgba, nil
+}
+
+// diffImage marks different pixels red on faded expected image
+func diffImage(expected, actual *image.RGBA) (*image.RGBA, int) {
+	diff := image.NewRGBA(actual.Bounds())
+	count := 0
+	for y := actual.Rect.Min.Y; y < actual.Rect.Max.Y; y++ {
+		for x := actual.Rect.Min.X; x < actual.Rect.Max.X; x++ {
+			e, a := expected.RGBAAt(x, y), actual.RGBAAt(x, y)
+			if e != a {
+				diff.SetRGBA(x, y, color.RGBA{0xFF, 0x00, 0x00, 0xFF})
+				count++
+				continue
+			}
+			gray := color.GrayModel.Convert(e).(color.Gray).Y
+			v := 0xFF - (0xFF-gray)/4
+			diff.SetRGBA(x, y, color.RGBA{v, v, v, 0xFF})
+		}
+	}
+	return diff, count
+}
+
+// assertGolden writes screen to test/actual, and compares it with
+// the golden image in test/expected pixel by pixel
+// Diff image is written to test/diff on mismatch
+func assertGolden(t *testing.T, name string, screen *image.RGBA) {
+	t.Helper()
+	file := name + ".png"
+	if err := writePNG(actualPath+file, screen); err != nil {
+		t.Fatal(err)
+	}
+
+	if *update {
+		if err := writePNG(expectedPath+file, screen); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	expected, err := readPNG(expectedPath + file)
+	if os.IsNotExist(err) {
+		t.Fatalf("golden image %s is not found, run with -update to create", file)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected.Bounds() != screen.Bounds() {
+		t.Fatalf("golden image %s is %v, but screen is %v", file, expected.Bounds(), screen.Bounds())
+	}
+
+	diff, count := diffImage(expected, screen)
+	if count == 0 {
+		os.Remove(diffPath + file)
+		return
+	}
+	if err := writePNG(diffPath+file, diff); err != nil {
+		t.Fatal(err)
+	}
+	t.Errorf("%d pixels differ from golden image %s, see %s", count, file, diffPath+file)
+}
@@ -0,0 +1,108 @@
+package gpu
+
+import (
+	"image/color"
+
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/memory"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/types"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/util"
+)
+
+// VRAM has 2 banks in CGB mode
+const VRAMBankSize = 0x2000
+
+// BG map attributes in VRAM bank 1, at the same address as tile index in bank 0
+// @see https://gbdev.io/pandocs/Tile_Maps.html#bg-map-attributes-cgb-mode-only
+type bgAttr byte
+
+// BG and Window colors 1-3 are drawn over sprites
+func (a bgAttr) priority() bool {
+	return util.Bit(byte(a), 7) == 1
+}
+
+func (a bgAttr) yFlip() bool {
+	return util.Bit(byte(a), 6) == 1
+}
+
+func (a bgAttr) xFlip() bool {
+	return util.Bit(byte(a), 5) == 1
+}
+
+// 0=Bank 0, 1=Bank 1
+func (a bgAttr) vramBank() byte {
+	return util.Bit(byte(a), 3)
+}
+
+func (a bgAttr) paletteNo() byte {
+	// bit 2-0
+	return byte(a) & 0x07
+}
+
+// SetCGBMode enables color palettes and BG map attributes
+// PPU reads both banks of vram regardless of the bank selected by CPU
+func (g *GPU) SetCGBMode(vram *memory.RAM) {
+	g.cgb = true
+	g.vram = vram
+	g.invalidateTiles()
+}
+
+// readVRAM reads VRAM of bank, bank is ignored in DMG mode
+func (g *GPU) readVRAM(addr types.Addr, bank byte) byte {
+	if !g.cgb {
+		return g.bus.ReadByte(addr)
+	}
+	return g.vram.Read(types.Addr(bank)*VRAMBankSize + addr - 0x8000)
+}
+
+// readBGAttr reads attributes of tile at addr of tile map, they are 0 in DMG mode
+func (g *GPU) readBGAttr(addr types.Addr) bgAttr {
+	if !g.cgb {
+		return 0
+	}
+	return bgAttr(g.readVRAM(addr, 1))
+}
+
+// bgColor applies palette to color index of BG and Window
+func (g *GPU) bgColor(c Color, a bgAttr) color.RGBA {
+	if g.cgb {
+		return g.palette.GetCGBPalette(c, a.paletteNo())
+	}
+	return g.palette.GetPalette(c)
+}
+
+// objColor applies OBJ palette no to color index of sprite
+func (g *GPU) objColor(c Color, no byte) color.RGBA {
+	if g.cgb {
+		return g.palette.GetCGBObjPalette(c, no)
+	}
+	return g.palette.GetObjPalette(c, uint(no))
+}
+
+// objPaletteNo is OBJ palette of sprite, OBP0/OBP1 in DMG mode
+func (g *GPU) objPaletteNo(s *Sprite) byte {
+	if g.cgb {
+		return s.CGBPaletteNo()
+	}
+	return s.MBGPalleteNo()
+}
+
+// objVRAMBank is VRAM bank of sprite tile, always 0 in DMG mode
+func (g *GPU) objVRAMBank(s *Sprite) byte {
+	if g.cgb {
+		return s.VRAMBank()
+	}
+	return 0
+}
+
+// objOverBG reports the sprite pixel is drawn over BG and Window of color bg
+// In CGB mode, LCDC bit 0 cleared makes sprites always drawn over
+// @see https://gbdev.io/pandocs/Tile_Maps.html#bg-to-obj-priority-in-cgb-mode
+func (g *GPU) objOverBG(bg Color, a bgAttr, objPriority bool) bool {
+	if bg == White {
+		return true
+	}
+	if g.cgb && !g.LCDC.BGWinEnable() {
+		return true
+	}
+	return !objPriority && !a.priority()
+}
@@ -3,7 +3,35 @@ package gpu
 import "github.com/Teshima-Tatsuya/GoBoy/pkg/types"
 
 const SPRITE_NUM = 40
+
+// sprites drawn in a line at most
+const SPRITE_PER_LINE = 10
 const CyclePerLine = 456
+
+// dots of each mode in a line
+// pixel transfer takes longer by SCX, and HBlank is the rest of the line
+// @see https://gbdev.io/pandocs/Rendering.html#ppu-modes
+const (
+	OAMScanCycles  = 80
+	TransferCycles = 172
+)
+
+// pixel transfer is extended when Window is drawn
+const windowPenalty = 6
+
+// Window is not drawn when WX > WX_MAX
+const WX_MAX = 166
+
+// LY 144-153 are VBlank
+const LinePerFrame = 154
+
+// LY is compared with LYC a M-cycle after LY changes
+// In line 153, LY changes to 0 at lyResetCycles
+// @see https://gbdev.io/pandocs/STAT.html#ff44--ly-lcd-y-coordinate-read-only
+const (
+	lyCompareCycles = 4
+	lyResetCycles   = 8
+)
 const (
 	SCREEN_WIDTH  = 160
 	SCREEN_HEIGHT = 144
@@ -12,22 +40,27 @@ const OAMSTARTAddr types.Addr = 0xFE00
 
 // Offset is FF00
 const (
-	LCDCAddr types.Addr = 0x40
-	LCDSAddr            = 0x41
-	SCYAddr             = 0x42
-	SCXAddr             = 0x43
-	LYAddr              = 0x44
-	LYCAddr             = 0x45
-	DMAAddr             = 0x46
-	BGPAddr             = 0x47
-	OBP0Addr            = 0x48
-	OBP1Addr            = 0x49
-	WYAddr              = 0x4A
-	WXAddr              = 0x4B
-	BCPSAddr            = 0x68
-	BCPDAddr            = 0x69
-	OCPSAddr            = 0x6A
-	OCPDAddr            = 0x6B
+	LCDCAddr  types.Addr = 0x40
+	LCDSAddr             = 0x41
+	SCYAddr              = 0x42
+	SCXAddr              = 0x43
+	LYAddr               = 0x44
+	LYCAddr              = 0x45
+	DMAAddr              = 0x46
+	BGPAddr              = 0x47
+	OBP0Addr             = 0x48
+	OBP1Addr             = 0x49
+	WYAddr               = 0x4A
+	WXAddr               = 0x4B
+	HDMA1Addr            = 0x51
+	HDMA2Addr            = 0x52
+	HDMA3Addr            = 0x53
+	HDMA4Addr            = 0x54
+	HDMA5Addr            = 0x55
+	BCPSAddr             = 0x68
+	BCPDAddr             = 0x69
+	OCPSAddr             = 0x6A
+	OCPDAddr             = 0x6B
 )
 
 type Mode byte
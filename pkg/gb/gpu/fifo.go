@@ -0,0 +1,350 @@
+package gpu
+
+import (
+	"sort"
+
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/state"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/types"
+)
+
+type Renderer byte
+
+const (
+	// ScanlineRenderer draws a whole line at the start of HBlank
+	// Tiles are decoded again only after VRAM is written
+	// Writes to registers in the middle of a line have no effect
+	ScanlineRenderer Renderer = iota
+	// FIFORenderer fetches tiles and pushes a pixel per dot in pixel transfer
+	// @see https://gbdev.io/pandocs/pixel_fifo.html
+	FIFORenderer
+)
+
+type fetcherState byte
+
+// each state takes 2 dots, and push waits for BG FIFO to be empty
+const (
+	fetchTile fetcherState = iota
+	fetchDataLow
+	fetchDataHigh
+	fetchPush
+)
+
+// the first tile is fetched twice at the start of a line
+const fetchDelay = 6
+
+// dots to fetch a sprite, while pixels are not pushed to LCD
+const spriteFetchCycles = 6
+
+// sprite is fetched after BG fetcher spent the dots for the current tile
+// @see https://gbdev.io/pandocs/Rendering.html#obj-penalty-algorithm
+const spriteWaitCycles = 5
+
+type objPixel struct {
+	color      Color
+	obp        byte
+	bgPriority bool
+	// index of the sprite in lineSprites, smaller is drawn over
+	priority uint8
+}
+
+type pixelFIFO struct {
+	bg    [16]Color
+	bgLen uint8
+	// pixels in BG FIFO are of a tile, since they are pushed when FIFO is empty
+	bgAttr bgAttr
+	obj    [8]objPixel
+
+	// fetcher
+	state fetcherState
+	dots  uint8
+	// tile column from the left of BG or Window
+	mapX      uint8
+	tileIdx   byte
+	attr      bgAttr
+	low, high byte
+	window    bool
+
+	// x of LCD the next pixel is pushed to
+	lx uint8
+	// pixels to be dropped, by SCX and WX < 7
+	discard uint8
+	delay   uint8
+
+	// indexes of lineSprites in the order of X, sprites are fetched in this order
+	order [SPRITE_PER_LINE]uint8
+	// index of order fetched next
+	nextSprite uint8
+	// remaining dots of sprite fetch
+	spriteFetch uint8
+}
+
+// SetRenderer selects how lines are drawn
+func (g *GPU) SetRenderer(r Renderer) {
+	g.renderer = r
+}
+
+// startTransfer resets FIFO at the start of pixel transfer
+func (g *GPU) startTransfer() {
+	g.fifo = pixelFIFO{
+		discard: g.Scroll.SCX % 8,
+		delay:   fetchDelay,
+	}
+
+	// WX=166 of the previous line makes Window span the entire line
+	// @see https://gbdev.io/pandocs/Scrolling.html#window
+	fullLine := g.winFullLine && g.LCDC.WindowEnable()
+	g.winFullLine = false
+	if fullLine && (g.cgb || g.LCDC.BGWinEnable()) {
+		g.fifo.window = true
+		g.fifo.discard = 0
+	}
+
+	// lineSprites are in OAM order in CGB mode
+	order := g.fifo.order[:len(g.lineSprites)]
+	for i := range order {
+		order[i] = uint8(i)
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return g.lineSprites[order[i]].x < g.lineSprites[order[j]].x
+	})
+}
+
+// fetchingSprite returns index of lineSprites fetched next
+func (f *pixelFIFO) fetchingSprite() uint8 {
+	return f.order[f.nextSprite]
+}
+
+// stepTransfer advances pixel transfer by a dot, and reports whether the line is done
+func (g *GPU) stepTransfer() bool {
+	f := &g.fifo
+	if f.delay > 0 {
+		f.delay--
+		return false
+	}
+
+	if f.spriteFetch > 0 {
+		f.spriteFetch--
+		if f.spriteFetch == 0 {
+			g.fetchSprite(f.fetchingSprite())
+			f.nextSprite++
+		}
+		return false
+	}
+
+	// Window is not drawn when BG is disabled, except CGB mode
+	bgEnabled := g.cgb || g.LCDC.BGWinEnable()
+	if !f.window && f.discard == 0 && bgEnabled && g.isWindowVisible() && int(f.lx) >= int(g.Scroll.WX)-7 {
+		// Window restarts fetcher from its first tile
+		f.window = true
+		f.bgLen = 0
+		f.state = fetchTile
+		f.dots = 0
+		f.mapX = 0
+		if g.Scroll.WX < 7 {
+			f.discard = 7 - g.Scroll.WX
+		}
+	}
+
+	g.stepFetcher()
+
+	// sprite waits for BG fetcher to almost fetch the next tile
+	// fetch of sprite starts in this dot
+	if f.discard == 0 && g.LCDC.OBJEnable() && int(f.nextSprite) < len(g.lineSprites) {
+		if s := g.lineSprites[f.fetchingSprite()]; s.X() <= int(f.lx) {
+			if f.fetchedCycles() >= spriteWait(s) && f.bgLen > 0 {
+				f.spriteFetch = spriteFetchCycles - 1
+			}
+			return false
+		}
+	}
+
+	if f.bgLen == 0 {
+		return false
+	}
+	c := f.popBG()
+	if f.discard > 0 {
+		f.discard--
+		return false
+	}
+	o := f.popObj()
+
+	if o.color != White && g.objOverBG(c, f.bgAttr, o.bgPriority) {
+		g.imageData[f.lx][g.Scroll.LY] = g.objColor(o.color, o.obp)
+	} else {
+		g.imageData[f.lx][g.Scroll.LY] = g.bgColor(c, f.bgAttr)
+	}
+	f.lx++
+	return f.lx == SCREEN_WIDTH
+}
+
+// stepFetcher advances BG/Window fetcher by a dot
+func (g *GPU) stepFetcher() {
+	f := &g.fifo
+	if f.state == fetchPush {
+		if f.bgLen == 0 {
+			for col := 0; col < 8; col++ {
+				b := 7 - col
+				if f.attr.xFlip() {
+					b = col
+				}
+				f.bg[f.bgLen] = Color((f.high>>b&1)<<1 | f.low>>b&1)
+				f.bgLen++
+			}
+			f.bgAttr = f.attr
+			f.state = fetchTile
+			f.mapX++
+		}
+		return
+	}
+
+	f.dots++
+	if f.dots < 2 {
+		return
+	}
+	f.dots = 0
+
+	switch f.state {
+	case fetchTile:
+		addr := g.fetchMapAddr()
+		f.tileIdx = g.readVRAM(addr, 0)
+		f.attr = g.readBGAttr(addr)
+	case fetchDataLow:
+		f.low = g.readVRAM(g.fetchDataAddr(), f.attr.vramBank())
+	case fetchDataHigh:
+		f.high = g.readVRAM(g.fetchDataAddr()+1, f.attr.vramBank())
+	}
+	f.state++
+
+	// BG is white when BG and Window are disabled, except CGB mode
+	if f.state == fetchPush && !g.cgb && !g.LCDC.BGWinEnable() {
+		f.low, f.high = 0, 0
+	}
+}
+
+// fetchMapAddr is address of tile index in tile map
+// registers are read every fetch, so that they take effect in the middle of a line
+func (g *GPU) fetchMapAddr() types.Addr {
+	f := &g.fifo
+	if f.window {
+		return types.Addr(g.LCDC.WinTileMapArea()) + types.Addr(g.winLine/8*32) + types.Addr(f.mapX&31)
+	}
+	y := (g.Scroll.LY + g.Scroll.SCY) & 255
+	x := (g.Scroll.SCX/8 + f.mapX) & 31
+	return types.Addr(g.LCDC.BGTileMapArea()) + types.Addr(y/8)*32 + types.Addr(x)
+}
+
+// fetchDataAddr is address of the lower byte of the row in tile
+func (g *GPU) fetchDataAddr() types.Addr {
+	f := &g.fifo
+	row := int(g.Scroll.LY+g.Scroll.SCY) % 8
+	if f.window {
+		row = g.winLine % 8
+	}
+	if f.attr.yFlip() {
+		row = 7 - row
+	}
+	if g.LCDC.BGWinTileDataArea() == BGWindowTileDataArea1 {
+		return 0x8000 + types.Addr(f.tileIdx)*16 + types.Addr(row*2)
+	}
+	// signed index from 0x9000
+	return types.Addr(0x9000+int(int8(f.tileIdx))*16) + types.Addr(row*2)
+}
+
+// fetchSprite mixes pixels of lineSprites[i] to sprite FIFO
+// Pixels already in FIFO are kept unless the sprite has higher priority
+func (g *GPU) fetchSprite(i uint8) {
+	f := &g.fifo
+	s := g.lineSprites[i]
+	tileIdx, row := g.spriteTile(s)
+	addr := 0x8000 + types.Addr(tileIdx*16+row*2)
+	bank := g.objVRAMBank(s)
+	low, high := g.readVRAM(addr, bank), g.readVRAM(addr+1, bank)
+
+	for col := 0; col < 8; col++ {
+		// pixels left of LCD are not pushed
+		p := s.X() + col - int(f.lx)
+		if p < 0 {
+			continue
+		}
+		b := 7 - col
+		if s.XFlip() {
+			b = col
+		}
+		c := Color((high>>b&1)<<1 | low>>b&1)
+		if c != White && (f.obj[p].color == White || i < f.obj[p].priority) {
+			f.obj[p] = objPixel{c, g.objPaletteNo(s), s.BGPriority(), i}
+		}
+	}
+}
+
+// spriteWait is dots fetcher must spend before sprite is fetched
+// Sprites partially hidden at the left edge are in the first tile dropped off screen
+func spriteWait(s *Sprite) uint8 {
+	switch {
+	case s.X() >= 0:
+		return spriteWaitCycles
+	case s.x >= spriteWaitCycles:
+		return 0
+	}
+	return spriteWaitCycles - s.x
+}
+
+// fetchedCycles is dots fetcher spent for the current tile
+func (f *pixelFIFO) fetchedCycles() uint8 {
+	return uint8(f.state)*2 + f.dots
+}
+
+func (f *pixelFIFO) popBG() Color {
+	c := f.bg[0]
+	copy(f.bg[:], f.bg[1:f.bgLen])
+	f.bgLen--
+	return c
+}
+
+func (f *pixelFIFO) popObj() objPixel {
+	o := f.obj[0]
+	copy(f.obj[:], f.obj[1:])
+	f.obj[7] = objPixel{}
+	return o
+}
+
+func (f *pixelFIFO) SaveState(w *state.Writer) {
+	w.Write(f.bg)
+	w.Write(f.bgLen)
+	w.Write(f.bgAttr)
+	for _, o := range f.obj {
+		w.Write(o.color)
+		w.Write(o.obp)
+		w.Write(o.bgPriority)
+		w.Write(o.priority)
+	}
+	w.Write([]byte{byte(f.state), f.dots, f.mapX, f.tileIdx, byte(f.attr), f.low, f.high})
+	w.Write(f.window)
+	w.Write(f.order)
+	w.Write([]byte{f.lx, f.discard, f.delay, f.nextSprite, f.spriteFetch})
+}
+
+func (f *pixelFIFO) LoadState(r *state.Reader) {
+	r.Read(&f.bg)
+	r.Read(&f.bgLen)
+	r.Read(&f.bgAttr)
+	for i := range f.obj {
+		r.Read(&f.obj[i].color)
+		r.Read(&f.obj[i].obp)
+		r.Read(&f.obj[i].bgPriority)
+		r.Read(&f.obj[i].priority)
+	}
+	r.Read(&f.state)
+	for _, v := range []*byte{&f.dots, &f.mapX, &f.tileIdx} {
+		r.Read(v)
+	}
+	r.Read(&f.attr)
+	for _, v := range []*byte{&f.low, &f.high} {
+		r.Read(v)
+	}
+	r.Read(&f.window)
+	r.Read(&f.order)
+	for _, v := range []*byte{&f.lx, &f.discard, &f.delay, &f.nextSprite, &f.spriteFetch} {
+		r.Read(v)
+	}
+}
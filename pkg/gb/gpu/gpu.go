@@ -3,36 +3,69 @@ package gpu
 import (
 	"image"
 	"image/color"
+	"sort"
 
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/debug"
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/interrupt"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/memory"
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/interfaces"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/state"
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/types"
 )
 
 type GPU struct {
 	bus        interfaces.Bus
 	requestIRQ func(byte)
-	clock      uint
-	imageData  [SCREEN_WIDTH][SCREEN_HEIGHT]color.RGBA
-	LCDC       *LCDC
-	LCDS       *LCDS
-	Scroll     *Scroll
-	palette    *Palette
-	DMA        byte
-	tiles      [3][128]Tile
+	// dot in the current line
+	clock uint
+	// length of pixel transfer in the current line
+	transferCycles uint
+	// ORed STAT interrupt sources, interrupt is requested on rising edge
+	statLine  bool
+	imageData [SCREEN_WIDTH][SCREEN_HEIGHT]color.RGBA
+	// color index and attributes of BG and Window in the current line, for sprite priority
+	bgColors [SCREEN_WIDTH]Color
+	bgAttrs  [SCREEN_WIDTH]bgAttr
+	// sprites selected by OAM scan of the current line, in drawing priority
+	lineSprites []*Sprite
+	// Window line counter
+	winLine int
+	// WY matched LY in the current frame
+	wyTriggered bool
+	// Window spans the current line by WX=166 of the previous line
+	winFullLine bool
+	renderer    Renderer
+	fifo        pixelFIFO
+	// CGB mode, vram has 2 banks
+	cgb     bool
+	vram    *memory.RAM
+	LCDC    *LCDC
+	LCDS    *LCDS
+	Scroll  *Scroll
+	palette *Palette
+	DMA     byte
+	tiles   [3][128]Tile
+	// tiles in VRAM bank 1, CGB only
+	cgbTiles [3][128]Tile
+	// tiles written since decoded, of each VRAM bank
+	dirtyTiles [2][3 * 128]bool
 	dmaStarted bool
+	hdma       hdma
 }
 
 func New() *GPU {
 	gpu := &GPU{
-		clock:   0,
-		LCDC:    NewLCDC(0x91),
-		LCDS:    NewLCDS(0x00),
-		Scroll:  NewScroll(),
-		palette: NewPalette(),
-		DMA:     0,
+		clock:          0,
+		transferCycles: TransferCycles,
+		lineSprites:    make([]*Sprite, 0, SPRITE_PER_LINE),
+		LCDC:           NewLCDC(0x91),
+		LCDS:           NewLCDS(0x00),
+		Scroll:         NewScroll(),
+		palette:        NewPalette(),
+		DMA:            0,
+		hdma:           newHDMA(),
 	}
+	gpu.invalidateTiles()
 
 	return gpu
 }
@@ -43,51 +76,182 @@ func (g *GPU) Init(bus interfaces.Bus, requestIRQ func(byte)) {
 }
 
 // gpu main process
+// cycles are dots, 4 dots per M-cycle
 func (g *GPU) Step(cycles uint) {
-	g.clock += cycles
-
 	if !g.LCDC.LCDPPUEnable() {
 		return
 	}
 
-	if g.clock >= CyclePerLine {
-		g.loadTile()
-		if g.Scroll.isVBlankStart() {
-			g.drawSplite()
-			g.requestIRQ(interrupt.VBlankFlag) // 1 is io.VBlankFlag, prepend cycle import...
-			if g.LCDS.Mode1() {
-				g.requestIRQ(interrupt.LCD_STATFlag)
-			}
-
-		} else if g.Scroll.isVBlankPeriod() {
+	for i := uint(0); i < cycles; i++ {
+		g.tick()
+	}
+}
 
-		} else if g.Scroll.isHBlankPeriod() {
-			if g.LCDS.Mode0() {
-				g.requestIRQ(interrupt.LCD_STATFlag)
+// tick advances 1 dot
+// A visible line is OAM scan(mode 2), pixel transfer(mode 3) and HBlank(mode 0)
+// @see https://gbdev.io/pandocs/Rendering.html#ppu-modes
+func (g *GPU) tick() {
+	g.clock++
+
+	switch {
+	case g.clock == CyclePerLine:
+		g.clock = 0
+		g.nextLine()
+	case g.clock == lyCompareCycles:
+		g.compareLY()
+	case g.LCDS.Mode() == Mode_VBlank:
+		// LY reads 0 from the early of line 153, and is compared with LYC again
+		if g.Scroll.LY == LinePerFrame-1 && g.clock == lyResetCycles {
+			g.Scroll.LY = 0
+			g.compareLY()
+		}
+	case g.clock == OAMScanCycles:
+		if g.Scroll.LY == g.Scroll.WY {
+			g.wyTriggered = true
+		}
+		g.scanOAM()
+		g.transferCycles = TransferCycles + uint(g.Scroll.SCX%8) + g.spritePenalty()
+		if g.isWindowVisible() {
+			g.transferCycles += windowPenalty
+		}
+		g.setMode(Mode_TransferringData)
+		if g.renderer == FIFORenderer {
+			g.startTransfer()
+		}
+	case g.LCDS.Mode() == Mode_TransferringData:
+		if g.renderer == FIFORenderer {
+			if g.stepTransfer() {
+				g.endTransfer()
 			}
-			// first build BG
-			// second build Window IF exists
-			g.drawBGLine()
+		} else if g.clock == OAMScanCycles+g.transferCycles {
+			g.endTransfer()
+		}
+	}
+}
 
-			if g.LCDC.WindowEnable() {
-				g.drawWinLine()
+// endTransfer enters HBlank, scanline renderer draws the line here
+func (g *GPU) endTransfer() {
+	g.setMode(Mode_HBlank)
+	g.requestHBlankDMA()
+	if g.renderer == FIFORenderer {
+		if g.fifo.window {
+			g.winLine++
+			if g.Scroll.WX == WX_MAX {
+				g.winFullLine = true
 			}
+		}
+		return
+	}
 
-		} else {
-			g.Scroll.LY = 0
-			g.drawBGLine()
+	g.loadTile()
+	// first build BG
+	// second build Window IF exists
+	g.drawBGLine()
+	g.drawWinLine()
+	g.drawSpriteLine()
+}
+
+// nextLine increments LY
+// LYC=LY flag is cleared until LY is compared at lyCompareCycles
+func (g *GPU) nextLine() {
+	// LY is already 0 at the end of line 153
+	if g.LCDS.Mode() == Mode_VBlank && g.Scroll.LY == 0 {
+		g.setMode(Mode_SearchingOAM)
+		return
+	}
+
+	g.Scroll.LY++
+	g.LCDS.Data &= 0xFB
+	if g.Scroll.isVBlankStart() {
+		// tiles are shown by Display
+		if g.renderer == FIFORenderer {
+			g.loadTile()
 		}
+		g.resetWindow()
+		g.requestIRQ(interrupt.VBlankFlag)
+		g.setMode(Mode_VBlank)
+	} else if g.Scroll.isHBlankPeriod() {
+		g.setMode(Mode_SearchingOAM)
+	} else {
+		g.updateSTAT()
+	}
+}
 
-		if g.Scroll.LY == g.Scroll.SCY {
-			g.LCDS.Data |= 0x04
-			if g.LCDS.LYC() {
-				g.requestIRQ(interrupt.LCD_STATFlag)
-			}
-		} else {
-			g.LCDS.Data &= 0xFB
+func (g *GPU) resetWindow() {
+	g.winLine = 0
+	g.wyTriggered = false
+	g.winFullLine = false
+}
+
+// compareLY sets LYC=LY flag of STAT
+// STAT interrupt is requested only when the flag rises, see updateSTAT
+// @see https://gbdev.io/pandocs/STAT.html#ff45--lyc-ly-compare
+func (g *GPU) compareLY() {
+	if g.Scroll.LY == g.Scroll.LYC {
+		g.LCDS.Data |= 0x04
+	} else {
+		g.LCDS.Data &= 0xFB
+	}
+	g.updateSTAT()
+}
+
+func (g *GPU) setMode(mode Mode) {
+	g.LCDS.Data = g.LCDS.Data&0xFC | byte(mode)
+	g.updateSTAT()
+}
+
+// updateSTAT requests STAT interrupt on rising edge of STAT line
+// Sources are ORed, so no interrupt is requested while another source holds the line
+// Mode 2 source is also triggered at the start of VBlank
+// @see https://gbdev.io/pandocs/Interrupt_Sources.html#int-48--stat-interrupt
+func (g *GPU) updateSTAT() {
+	line := false
+	switch g.LCDS.Mode() {
+	case Mode_HBlank:
+		line = g.LCDS.Mode0()
+	case Mode_VBlank:
+		line = g.LCDS.Mode1() || (g.LCDS.Mode2() && g.Scroll.isVBlankStart())
+	case Mode_SearchingOAM:
+		line = g.LCDS.Mode2()
+	}
+	if g.LCDS.LYC() && g.LCDS.LYCLY() {
+		line = true
+	}
+
+	if line && !g.statLine {
+		g.requestIRQ(interrupt.LCD_STATFlag)
+	}
+	g.statLine = line
+}
+
+// setLCDC handles LCD on/off
+// LY is reset while LCD is off, and the first line after turning on has no OAM scan
+func (g *GPU) setLCDC(value byte) {
+	enabled := g.LCDC.LCDPPUEnable()
+	g.LCDC.Data = value
+	switch {
+	case enabled && !g.LCDC.LCDPPUEnable():
+		g.clock = 0
+		g.Scroll.LY = 0
+		g.LCDS.Data &= 0xFC
+		g.statLine = false
+	case !enabled && g.LCDC.LCDPPUEnable():
+		g.transferCycles = TransferCycles
+		g.resetWindow()
+		g.compareLY()
+	}
+}
+
+// Blank makes the screen white, since LCD shows nothing in STOP mode
+func (g *GPU) Blank() {
+	white := palette[0]
+	if g.cgb {
+		white = color.RGBA{255, 255, 255, 255}
+	}
+	for x := range g.imageData {
+		for y := range g.imageData[x] {
+			g.imageData[x][y] = white
 		}
-		g.Scroll.LY++
-		g.clock -= CyclePerLine
 	}
 }
 
@@ -115,107 +279,226 @@ func (g *GPU) Display() (*image.RGBA, *image.RGBA) {
 }
 
 func (g *GPU) loadTile() {
-	addr := 0x8000
-	// todo CGBMode
-	tileNum := 128
+	g.loadTileBank(&g.tiles, 0)
+	if g.cgb {
+		g.loadTileBank(&g.cgbTiles, 1)
+	}
+}
+
+// loadTileBank decodes only tiles written since the last load
+func (g *GPU) loadTileBank(tiles *[3][128]Tile, bank byte) {
 	var bytes16 [16]byte
 
 	// One tile occupies 16 bytes
-	for block := 0; block < 3; block++ {
-		for i := 0; i < tileNum; i++ {
-			for b := 0; b < 16; b++ {
-				bytes16[b] = g.bus.ReadByte(types.Addr(addr) + types.Addr(block*128*16+i*16+b))
-			}
-			g.tiles[block][i] = *NewTile(bytes16[:])
+	for i, dirty := range g.dirtyTiles[bank] {
+		if !dirty {
+			continue
+		}
+		addr := types.Addr(0x8000 + i*16)
+		for b := 0; b < 16; b++ {
+			bytes16[b] = g.readVRAM(addr+types.Addr(b), bank)
+		}
+		tiles[i/128][i%128] = *NewTile(bytes16[:])
+		g.dirtyTiles[bank][i] = false
+	}
+}
+
+// InvalidateTile marks the tile at addr of VRAM bank to be decoded again
+// Bus calls this on every write to VRAM
+func (g *GPU) InvalidateTile(addr types.Addr, bank byte) {
+	if addr >= 0x8000 && addr < 0x9800 {
+		g.dirtyTiles[bank&1][(addr-0x8000)/16] = true
+	}
+}
+
+// invalidateTiles marks all tiles, e.g. VRAM is replaced by LoadState
+func (g *GPU) invalidateTiles() {
+	for bank := range g.dirtyTiles {
+		for i := range g.dirtyTiles[bank] {
+			g.dirtyTiles[bank][i] = true
 		}
 	}
 }
 
+// tileBank returns cached tiles of VRAM bank
+func (g *GPU) tileBank(bank byte) *[3][128]Tile {
+	if bank == 1 {
+		return &g.cgbTiles
+	}
+	return &g.tiles
+}
+
 // Step1: get tile id from tile map
 // Step2: get color form tile id
 // Step3: Store color to imageData
 func (g *GPU) drawBGLine() {
 	for x := 0; x < SCREEN_WIDTH; x++ {
-		g.imageData[x][g.Scroll.LY] = g.getBGTileColor(x)
+		c, a := g.getBGTileColor(x)
+		g.bgColors[x] = c
+		g.bgAttrs[x] = a
+		g.imageData[x][g.Scroll.LY] = g.bgColor(c, a)
 	}
 }
 
+// drawWinLine draws Window from WX-7 to the right
+// Window has its own line counter, which is incremented only when Window is drawn
+// @see https://gbdev.io/pandocs/Scrolling.html#window
 func (g *GPU) drawWinLine() {
-	if (g.Scroll.WX < 0 || 167 <= g.Scroll.WX) ||
-		(g.Scroll.WY < 0 || 144 <= g.Scroll.WY) {
+	fullLine := g.winFullLine && g.LCDC.WindowEnable()
+	g.winFullLine = false
+	if !g.isWindowVisible() && !fullLine {
 		return
 	}
-	if g.Scroll.LY < g.Scroll.WY {
-		return
+
+	// WX < 7 shifts Window to the left
+	left := int(g.Scroll.WX) - 7
+	if fullLine {
+		left = 0
 	}
 	for x := 0; x < SCREEN_WIDTH; x++ {
-		g.imageData[x][g.Scroll.LY] = g.getWinTileColor(x)
+		if x < left {
+			continue
+		}
+		c, a := g.getWinTileColor(x - left)
+		g.bgColors[x] = c
+		g.bgAttrs[x] = a
+		g.imageData[x][g.Scroll.LY] = g.bgColor(c, a)
 	}
+	g.winLine++
+
+	// WX=166 makes Window span the entire next line
+	if g.Scroll.WX == WX_MAX {
+		g.winFullLine = true
+	}
+}
+
+// isWindowVisible reports Window is drawn in the current line
+// Window is triggered when WY matched LY at some point in the frame
+func (g *GPU) isWindowVisible() bool {
+	return g.LCDC.WindowEnable() && g.wyTriggered && g.Scroll.WX <= WX_MAX
 }
 
-func (g *GPU) drawSplite() {
-	for i := 0; i < SPRITE_NUM; i++ {
-		bytes4 := [4]byte{}
+// scanOAM selects sprites on the current line in OAM order
+// @see https://gbdev.io/pandocs/OAM.html#selection-priority
+func (g *GPU) scanOAM() {
+	g.lineSprites = g.lineSprites[:0]
+	height := g.objHeight()
+	for i := 0; i < SPRITE_NUM && len(g.lineSprites) < SPRITE_PER_LINE; i++ {
+		var bytes4 [4]byte
 		for j := 0; j < 4; j++ {
-			addr := OAMSTARTAddr + types.Addr(i*4) + types.Addr(j)
-			bytes4[j] = g.bus.ReadByte(addr)
+			bytes4[j] = g.bus.ReadByte(OAMSTARTAddr + types.Addr(i*4+j))
 		}
-
 		s := NewSprite(bytes4[:])
+		if y := int(g.Scroll.LY) - s.Y(); 0 <= y && y < height {
+			g.lineSprites = append(g.lineSprites, s)
+		}
+	}
 
-		var objHeight int
-		objHeight = 8
-		// if g.LCDC.OBJSize() == 1 {
-		// 	objHeight = 16
-		// } else {
-		// 	objHeight = 8
-		// }
-
-		for x := 0; x < 8; x++ {
-			for y := 0; y < objHeight; y++ {
-				xPos := int(s.x) + x
-				yPos := int(s.y) + y
-
-				// ignore out of screen
-				if (xPos < 0 || SCREEN_WIDTH <= xPos) ||
-					(yPos < 0 || SCREEN_HEIGHT <= yPos) {
-					continue
-				}
+	// smaller X is drawn over, and earlier in OAM is drawn over when X is the same
+	// In CGB mode, only OAM order matters
+	if g.cgb {
+		return
+	}
+	sort.SliceStable(g.lineSprites, func(i, j int) bool {
+		return g.lineSprites[i].x < g.lineSprites[j].x
+	})
+}
 
-				var block int
-				var tileIdx int
-				if s.tileIdx >= 128 {
-					block = 1
-					tileIdx = int(s.tileIdx) - 128
-				} else {
-					block = 0
-					tileIdx = int(s.tileIdx)
-				}
+func (g *GPU) objHeight() int {
+	if g.LCDC.OBJSize() == 1 {
+		return 16
+	}
+	return 8
+}
 
-				tile := g.tiles[block][tileIdx]
+// spritePenalty is dots pixel transfer is extended by sprites on the line
+// @see https://gbdev.io/pandocs/Rendering.html#obj-penalty-algorithm
+func (g *GPU) spritePenalty() uint {
+	if !g.LCDC.OBJEnable() {
+		return 0
+	}
 
-				if s.YFlip() {
-					y = 7 - y
-				}
-				yPos = int(s.y) + y
-				if s.XFlip() {
-					x = 7 - x
-				}
-				xPos = int(s.x) + x
+	// sprites are fetched in the order of X
+	var sprites [SPRITE_PER_LINE]*Sprite
+	fetched := sprites[:len(g.lineSprites)]
+	copy(fetched, g.lineSprites)
+	sort.SliceStable(fetched, func(i, j int) bool {
+		return fetched[i].x < fetched[j].x
+	})
+
+	var penalty uint
+	var considered [32]bool
+	for _, s := range fetched {
+		switch {
+		case s.x == 0:
+			penalty += 11
+			continue
+		case SCREEN_WIDTH+8 <= s.x:
+			// not fetched
+			continue
+		}
+		// leftmost pixel of sprite, counted from the first tile fetched off screen
+		pixel := int(s.x) + int(g.Scroll.SCX%8)
+		if tile := pixel / 8; !considered[tile] {
+			considered[tile] = true
+			if right := 7 - pixel%8; right > 2 {
+				penalty += uint(right - 2)
+			}
+		}
+		penalty += 6
+	}
+	return penalty
+}
 
-				c := tile.Data[x][y]
+// spriteTile returns tile index and row in the tile of sprite at the current line
+// Y flip is applied, and the tile is the lower one of 8x16 sprite below 8 pixels
+func (g *GPU) spriteTile(s *Sprite) (int, int) {
+	height := g.objHeight()
+	row := int(g.Scroll.LY) - s.Y()
+	if s.YFlip() {
+		row = height - 1 - row
+	}
 
-				if c != 0 {
-					p := g.palette.GetObjPalette(c, uint(s.MBGPalleteNo()))
-					g.imageData[xPos][yPos] = p
-				}
+	tileIdx := int(s.tileIdx)
+	if height == 16 {
+		tileIdx = tileIdx&0xFE + row/8
+	}
+	return tileIdx, row % 8
+}
+
+// drawSpriteLine draws sprites over BG and Window of the current line
+func (g *GPU) drawSpriteLine() {
+	if !g.LCDC.OBJEnable() {
+		return
+	}
+
+	ly := int(g.Scroll.LY)
+	for x := 0; x < SCREEN_WIDTH; x++ {
+		for _, s := range g.lineSprites {
+			col := x - s.X()
+			if col < 0 || 8 <= col {
+				continue
+			}
+			if s.XFlip() {
+				col = 7 - col
+			}
+
+			tileIdx, row := g.spriteTile(s)
+			c := g.tileBank(g.objVRAMBank(s))[tileIdx/128][tileIdx%128].Data[row][col]
+			// transparent, sprites behind are drawn
+			if c == White {
+				continue
+			}
+
+			if g.objOverBG(g.bgColors[x], g.bgAttrs[x], s.BGPriority()) {
+				g.imageData[x][ly] = g.objColor(c, g.objPaletteNo(s))
 			}
+			break
 		}
 	}
-
 }
 
-func (g *GPU) getBGTileColor(LX int) color.RGBA {
+func (g *GPU) getBGTileColor(LX int) (Color, bgAttr) {
 	// yPos is current pixel from top(0-255)
 	yPos := (g.Scroll.LY + g.Scroll.SCY) & 255
 	xPos := (LX + int(g.Scroll.SCX)) & 255
@@ -224,16 +507,17 @@ func (g *GPU) getBGTileColor(LX int) color.RGBA {
 	return g.getTileColor(xPos, int(yPos), types.Addr(baseAddr))
 }
 
-func (g *GPU) getWinTileColor(LX int) color.RGBA {
-	// yPos is current pixel from top(0-255)
-	yPos := g.Scroll.LY - g.Scroll.WY
-	xPos := LX - (int(g.Scroll.WX) - 7)
+// xPos is pixel from the left of Window
+func (g *GPU) getWinTileColor(xPos int) (Color, bgAttr) {
+	// yPos is Window line counter
+	yPos := g.winLine
 	baseAddr := g.LCDC.WinTileMapArea()
 
 	return g.getTileColor(int(xPos), int(yPos), types.Addr(baseAddr))
 }
 
-func (g *GPU) getTileColor(xPos, yPos int, baseAddr types.Addr) color.RGBA {
+// getTileColor returns color index and attributes of the pixel, palette is not applied
+func (g *GPU) getTileColor(xPos, yPos int, baseAddr types.Addr) (Color, bgAttr) {
 	// https://gbdev.io/pandocs/pixel_fifo.html#get-tile
 
 	// yTile is Tile corresponding at yPos
@@ -242,7 +526,8 @@ func (g *GPU) getTileColor(xPos, yPos int, baseAddr types.Addr) color.RGBA {
 	xTile := xPos / 8
 
 	addr := types.Addr(baseAddr) + types.Addr(yTile)*32 + types.Addr(xTile)
-	tileIdx := int(int8(g.bus.ReadByte(addr)))
+	tileIdx := int(int8(g.readVRAM(addr, 0)))
+	attr := g.readBGAttr(addr)
 
 	var block int
 	block = 0
@@ -254,6 +539,8 @@ func (g *GPU) getTileColor(xPos, yPos int, baseAddr types.Addr) color.RGBA {
 			block = 2
 		}
 	} else {
+		// tile index is unsigned
+		tileIdx &= 0xFF
 		if tileIdx < 128 {
 			block = 0
 		} else {
@@ -262,7 +549,14 @@ func (g *GPU) getTileColor(xPos, yPos int, baseAddr types.Addr) color.RGBA {
 		}
 	}
 
-	return g.palette.GetPalette(g.tiles[block][tileIdx].Data[yPos%8][xPos%8])
+	row, col := yPos%8, xPos%8
+	if attr.yFlip() {
+		row = 7 - row
+	}
+	if attr.xFlip() {
+		col = 7 - col
+	}
+	return g.tileBank(attr.vramBank())[block][tileIdx].Data[row][col], attr
 }
 
 func (g *GPU) ImageData() ([SCREEN_WIDTH][SCREEN_HEIGHT]color.RGBA, [3][128]Tile) {
@@ -294,8 +588,10 @@ func (g *GPU) Read(addr types.Addr) byte {
 		return g.Scroll.Read(addr)
 	case DMAAddr:
 		return g.DMA
-	case BGPAddr, OBP0Addr, OBP1Addr:
+	case BGPAddr, OBP0Addr, OBP1Addr, BCPSAddr, BCPDAddr, OCPSAddr, OCPDAddr:
 		return g.palette.Read(addr)
+	case HDMA1Addr, HDMA2Addr, HDMA3Addr, HDMA4Addr, HDMA5Addr:
+		return g.hdma.read(addr)
 	default:
 		debug.Fatal("GPU Read 0x%04X", addr)
 	}
@@ -305,17 +601,82 @@ func (g *GPU) Read(addr types.Addr) byte {
 func (g *GPU) Write(addr types.Addr, value byte) {
 	switch addr {
 	case LCDCAddr:
-		g.LCDC.Data = value
+		g.setLCDC(value)
 	case LCDSAddr:
-		g.LCDS.Data = value & 0x7F
-	case SCYAddr, SCXAddr, LYAddr, LYCAddr, WXAddr, WYAddr:
+		// mode and LYC=LY flag are read only
+		g.LCDS.Data = g.LCDS.Data&0x07 | value&0x78
+		g.updateSTAT()
+	case SCYAddr, SCXAddr, LYAddr, WXAddr, WYAddr:
+		g.Scroll.Write(addr, value)
+	case LYCAddr:
 		g.Scroll.Write(addr, value)
+		if g.LCDC.LCDPPUEnable() {
+			g.compareLY()
+		}
 	case DMAAddr:
 		g.dmaStarted = true
 		g.DMA = value
-	case BGPAddr, OBP0Addr, OBP1Addr:
+	case BGPAddr, OBP0Addr, OBP1Addr, BCPSAddr, BCPDAddr, OCPSAddr, OCPDAddr:
 		g.palette.Write(addr, value)
+	case HDMA1Addr, HDMA2Addr, HDMA3Addr, HDMA4Addr, HDMA5Addr:
+		g.writeHDMA(addr, value)
 	default:
 		debug.Fatal("GPU Write 0x%04X", addr)
 	}
 }
+
+// tiles are not saved, since they are decoded from VRAM again after LoadState
+func (g *GPU) SaveState(w *state.Writer) {
+	w.Write(uint32(g.clock))
+	w.Write(uint32(g.transferCycles))
+	w.Write(g.statLine)
+	w.Write(uint8(g.winLine))
+	w.Write(g.wyTriggered)
+	w.Write(g.winFullLine)
+	w.Write(uint8(len(g.lineSprites)))
+	for _, s := range g.lineSprites {
+		w.Write(s.Bytes())
+	}
+	w.Write(g.LCDC.Data)
+	w.Write(g.LCDS.Data)
+	g.Scroll.SaveState(w)
+	g.palette.SaveState(w)
+	w.Write(g.DMA)
+	w.Write(g.dmaStarted)
+	w.Write(&g.imageData)
+	g.fifo.SaveState(w)
+	g.hdma.SaveState(w)
+}
+
+func (g *GPU) LoadState(r *state.Reader) {
+	var clock uint32
+	r.Read(&clock)
+	g.clock = uint(clock)
+	var transferCycles uint32
+	r.Read(&transferCycles)
+	g.transferCycles = uint(transferCycles)
+	r.Read(&g.statLine)
+	var winLine uint8
+	r.Read(&winLine)
+	g.winLine = int(winLine)
+	r.Read(&g.wyTriggered)
+	r.Read(&g.winFullLine)
+	var n uint8
+	r.Read(&n)
+	g.lineSprites = g.lineSprites[:0]
+	for i := 0; i < int(n) && i < SPRITE_PER_LINE; i++ {
+		var bytes4 [4]byte
+		r.Read(&bytes4)
+		g.lineSprites = append(g.lineSprites, NewSprite(bytes4[:]))
+	}
+	r.Read(&g.LCDC.Data)
+	r.Read(&g.LCDS.Data)
+	g.Scroll.LoadState(r)
+	g.palette.LoadState(r)
+	r.Read(&g.DMA)
+	r.Read(&g.dmaStarted)
+	r.Read(&g.imageData)
+	g.fifo.LoadState(r)
+	g.hdma.LoadState(r)
+	g.invalidateTiles()
+}
@@ -1,6 +1,7 @@
 package gpu
 
 import (
+	"image/color"
 	"testing"
 
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/interrupt"
@@ -9,51 +10,6 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-func setupGPU1() *GPU {
-	g := New()
-	b := mock.NewMockBus()
-	bytes1 := []byte{0xFF, 0x00, 0x7E, 0xFF, 0x85, 0x81, 0x89, 0x83, 0x93, 0x85, 0xA5, 0x8B, 0xC9, 0x97, 0x7E, 0xFF}
-	bytes2 := []byte{0x00, 0x00, 0x7E, 0xFF, 0x85, 0x81, 0x89, 0x83, 0x93, 0x85, 0xA5, 0x8B, 0xC9, 0x97, 0x7E, 0xFF}
-
-	for tile := 0; tile < 255; tile++ {
-		baseAddr := types.Addr(0x8800) + types.Addr(tile*16)
-		if tile%2 == 0 {
-			for i := 0; i < len(bytes1); i++ {
-				b.WriteByte(baseAddr+types.Addr(i), bytes1[i])
-			}
-		} else {
-			for i := 0; i < len(bytes2); i++ {
-				b.WriteByte(baseAddr+types.Addr(i), bytes2[i])
-			}
-		}
-	}
-	g.Init(b, interrupt.NewIRQ().Request)
-
-	return g
-}
-
-func setupGPU2() *GPU {
-	g := New()
-	b := mock.NewMockBus()
-	bytes1 := []byte{0xFF, 0x00, 0x7E, 0xFF, 0x85, 0x81, 0x89, 0x83, 0x93, 0x85, 0xA5, 0x8B, 0xC9, 0x97, 0x7E, 0xFF}
-	bytes2 := []byte{0x00, 0x00, 0x7E, 0xFF, 0x85, 0x81, 0x89, 0x83, 0x93, 0x85, 0xA5, 0x8B, 0xC9, 0x97, 0x7E, 0xFF}
-
-	for tile := 0; tile < 255; tile++ {
-		baseAddr := types.Addr(0x8000) + types.Addr(tile*16)
-		if tile%2 == 0 {
-			for i := 0; i < len(bytes2); i++ {
-				b.WriteByte(baseAddr+types.Addr(i), bytes2[i])
-			}
-		} else {
-			for i := 0; i < len(bytes1); i++ {
-				b.WriteByte(baseAddr+types.Addr(i), bytes1[i])
-			}
-		}
-	}
-	g.Init(b, io.NewIRQ().Request)
-
-	return g
-}
 func TestGPU_loadTile(t *testing.T) {
 	t.Run("load tile", func(t *testing.T) {
 
@@ -78,40 +34,775 @@ func TestGPU_loadTile(t *testing.T) {
 			{DarkGray, Black, Black, Black, Black, Black, Black, DarkGray},
 		}
 
-		t.Run("tile data 0", func(t *testing.T) {
-			g := setupGPU1()
-			g.LCDC.Data = 0x00 // tile data starts 0x8800
-			g.loadTile()
+		bytes1 := []byte{0xFF, 0x00, 0x7E, 0xFF, 0x85, 0x81, 0x89, 0x83, 0x93, 0x85, 0xA5, 0x8B, 0xC9, 0x97, 0x7E, 0xFF}
+		bytes2 := []byte{0x00, 0x00, 0x7E, 0xFF, 0x85, 0x81, 0x89, 0x83, 0x93, 0x85, 0xA5, 0x8B, 0xC9, 0x97, 0x7E, 0xFF}
 
-			for i := 0; i < 255; i++ {
-				if i <= 127 {
-					if i%2 == 0 {
-						assert.Equal(t, colors1, g.tiles[0][i].Data)
-					} else {
-						assert.Equal(t, colors2, g.tiles[0][i].Data)
-					}
-				} else {
-					if i%2 == 0 {
-						assert.Equal(t, colors1, g.tiles[1][i].Data)
-					} else {
-						assert.Equal(t, colors2, g.tiles[1][i].Data)
+		tests := []struct {
+			name string
+			lcdc byte
+			addr types.Addr
+			// block of the first tile
+			block int
+			// tiles of even and odd index
+			data   [2][]byte
+			colors [2][8][8]Color
+		}{
+			// 0x8800-0x97FF is block 1 and 2
+			{"tile data 0", 0x00, 0x8800, 1, [2][]byte{bytes1, bytes2}, [2][8][8]Color{colors1, colors2}},
+			{"tile data 1", 0x10, 0x8000, 0, [2][]byte{bytes2, bytes1}, [2][8][8]Color{colors2, colors1}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				g, b, _ := setupGPU(tt.lcdc)
+				for tile := 0; tile < 255; tile++ {
+					for i, v := range tt.data[tile%2] {
+						b.WriteByte(tt.addr+types.Addr(tile*16+i), v)
 					}
+				}
+				g.loadTile()
 
+				for i := 0; i < 255; i++ {
+					assert.Equal(t, tt.colors[i%2], g.tiles[tt.block+i/128][i%128].Data)
 				}
+			})
+		}
+	})
+
+	t.Run("decode only invalidated tiles", func(t *testing.T) {
+		g, b, _ := setupGPU(0x91)
+		g.loadTile()
+		assert.Equal(t, Color(1), g.tiles[0][1].Data[0][0])
+
+		// tile 1 and 2 are rewritten to color 0, but only tile 1 is invalidated
+		for i := 0; i < 32; i++ {
+			b.WriteByte(types.Addr(0x8010+i), 0)
+		}
+		g.InvalidateTile(0x801F, 0)
+		g.loadTile()
+		assert.Equal(t, Color(0), g.tiles[0][1].Data[0][0])
+		assert.Equal(t, Color(2), g.tiles[0][2].Data[0][0])
+	})
+}
+
+func TestGPU_Step_mode(t *testing.T) {
+	g, _, _ := setupGPU(0x91)
+	g.Write(LCDCAddr, 0x00)
+	g.Write(LCDCAddr, 0x91)
+	g.Write(SCXAddr, 3)
+
+	// first line after LCD on has no OAM scan
+	assert.Equal(t, Mode_HBlank, g.LCDS.Mode())
+	g.Step(OAMScanCycles)
+	assert.Equal(t, Mode_TransferringData, g.LCDS.Mode())
+	g.Step(TransferCycles + 2)
+	assert.Equal(t, Mode_TransferringData, g.LCDS.Mode())
+	g.Step(1)
+	assert.Equal(t, Mode_HBlank, g.LCDS.Mode())
+
+	g.Step(CyclePerLine - OAMScanCycles - TransferCycles - 3)
+	assert.Equal(t, byte(1), g.Scroll.LY)
+	assert.Equal(t, Mode_SearchingOAM, g.LCDS.Mode())
+	assert.Equal(t, byte(0x82), g.Read(LCDSAddr)&0x83)
+
+	g.Step(CyclePerLine * 143)
+	assert.Equal(t, byte(SCREEN_HEIGHT), g.Scroll.LY)
+	assert.Equal(t, Mode_VBlank, g.LCDS.Mode())
+
+	g.Step(CyclePerLine * 10)
+	assert.Equal(t, byte(0), g.Scroll.LY)
+	assert.Equal(t, Mode_SearchingOAM, g.LCDS.Mode())
+
+	t.Run("LCD off", func(t *testing.T) {
+		g.Step(100)
+		g.Write(LCDCAddr, 0x11)
+		assert.Equal(t, Mode_HBlank, g.LCDS.Mode())
+		assert.Equal(t, byte(0), g.Scroll.LY)
+		g.Step(CyclePerLine * 2)
+		assert.Equal(t, Mode_HBlank, g.LCDS.Mode())
+		assert.Equal(t, byte(0), g.Scroll.LY)
+	})
+}
+
+func TestGPU_Step_STAT(t *testing.T) {
+	const frame = CyclePerLine * LinePerFrame
+	hblank := uint(OAMScanCycles + TransferCycles)
+	vblank := uint(CyclePerLine * SCREEN_HEIGHT)
+
+	tests := []struct {
+		name string
+		stat byte
+		// number of interrupts in a frame, and the first and the last dot of them
+		count       int
+		first, last uint
+	}{
+		{"HBlank", 0x08, SCREEN_HEIGHT, hblank, CyclePerLine*(SCREEN_HEIGHT-1) + hblank},
+		{"VBlank", 0x10, 1, vblank, vblank},
+		// also requested at the start of VBlank, first line has no OAM scan after LCD on
+		{"OAM", 0x20, SCREEN_HEIGHT + 1, CyclePerLine, frame},
+		// OAM scan starts while HBlank holds the line
+		{"HBlank and OAM", 0x28, SCREEN_HEIGHT + 1, hblank, frame},
+		// OAM scan of the next frame starts while VBlank holds the line
+		{"VBlank and OAM", 0x30, SCREEN_HEIGHT, CyclePerLine, vblank},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, _, irq := setupGPU(0x91)
+			// LYC=LY never matches
+			g.Write(LYCAddr, 0xFF)
+			g.Write(LCDSAddr, tt.stat)
+			irq.IF = 0
+
+			var got []uint
+			for dot := uint(1); dot <= frame; dot++ {
+				g.Step(1)
+				if irq.IF&interrupt.LCD_STATFlag != 0 {
+					got = append(got, dot)
+					irq.IF = 0
+				}
+			}
+			assert.Equal(t, tt.count, len(got))
+			if len(got) > 0 {
+				assert.Equal(t, tt.first, got[0])
+				assert.Equal(t, tt.last, got[len(got)-1])
+			}
+		})
+	}
+
+	t.Run("VBlank", func(t *testing.T) {
+		g, _, irq := setupGPU(0x91)
+		g.Step(CyclePerLine*SCREEN_HEIGHT - 1)
+		assert.Equal(t, byte(0), irq.IF&interrupt.VBlankFlag)
+		g.Step(1)
+		assert.Equal(t, interrupt.VBlankFlag, irq.IF&interrupt.VBlankFlag)
+	})
+}
+
+// stepSTAT steps GPU a dot at a time for cycles, and returns dots when STAT interrupt is requested
+// onIRQ is called on each interrupt like an interrupt handler
+func TestGPU_Step_LYC(t *testing.T) {
+	const frame = CyclePerLine * LinePerFrame
+	line := func(ly uint) uint {
+		return CyclePerLine*ly + lyCompareCycles
+	}
+
+	t.Run("raster split", func(t *testing.T) {
+		g, _, irq := setupGPU(0x91)
+		g.Write(LYCAddr, 40)
+		g.Write(LCDSAddr, 0x40)
+
+		// the handler sets the next split and scrolls the rest of the screen
+		splits := []byte{80, 120, 40}
+		var scx []byte
+		got := stepSTAT(g, irq, frame*2, func() {
+			scx = append(scx, g.Scroll.LY)
+			g.Write(SCXAddr, g.Scroll.LY)
+			g.Write(LYCAddr, splits[0])
+			splits = append(splits[1:], splits[0])
+		})
+		assert.Equal(t, []uint{line(40), line(80), line(120), frame + line(40), frame + line(80), frame + line(120)}, got)
+		assert.Equal(t, []byte{40, 80, 120, 40, 80, 120}, scx)
+	})
+
+	t.Run("LYC=0 in line 153", func(t *testing.T) {
+		g, _, irq := setupGPU(0x91)
+		g.Write(LYCAddr, 0)
+		g.Write(LCDSAddr, 0x40)
+		irq.IF = 0
+
+		got := stepSTAT(g, irq, frame, nil)
+		assert.Equal(t, []uint{CyclePerLine*153 + lyResetCycles}, got)
+	})
+
+	t.Run("LYC=153", func(t *testing.T) {
+		g, _, irq := setupGPU(0x91)
+		g.Write(LYCAddr, 153)
+		g.Write(LCDSAddr, 0x40)
+
+		got := stepSTAT(g, irq, frame, nil)
+		assert.Equal(t, []uint{line(153)}, got)
+	})
+
+	t.Run("LY in line 153", func(t *testing.T) {
+		g, _, _ := setupGPU(0x91)
+		g.Step(CyclePerLine*153 + lyResetCycles - 1)
+		assert.Equal(t, byte(153), g.Read(LYAddr))
+		g.Step(1)
+		assert.Equal(t, byte(0), g.Read(LYAddr))
+		g.Step(CyclePerLine - lyResetCycles)
+		assert.Equal(t, byte(0), g.Read(LYAddr))
+		assert.Equal(t, Mode_SearchingOAM, g.LCDS.Mode())
+		g.Step(CyclePerLine)
+		assert.Equal(t, byte(1), g.Read(LYAddr))
+	})
+
+	t.Run("rising edge only", func(t *testing.T) {
+		g, _, irq := setupGPU(0x91)
+		g.Write(LYCAddr, 40)
+		// LYC and HBlank
+		g.Write(LCDSAddr, 0x48)
+		irq.IF = 0
+
+		got := stepSTAT(g, irq, frame, nil)
+		hblank := uint(OAMScanCycles + TransferCycles)
+		// HBlank of line 40 is blocked by LYC=LY
+		assert.Contains(t, got, line(40))
+		assert.NotContains(t, got, CyclePerLine*40+hblank)
+		assert.Contains(t, got, CyclePerLine*41+hblank)
+		assert.Equal(t, SCREEN_HEIGHT, len(got))
+	})
+
+	t.Run("write LYC", func(t *testing.T) {
+		g, _, irq := setupGPU(0x91)
+		g.Write(LCDSAddr, 0x40)
+		g.Step(CyclePerLine * 10)
+		irq.IF = 0
+		assert.Equal(t, byte(0x00), g.Read(LCDSAddr)&0x04)
+
+		g.Write(LYCAddr, 10)
+		assert.Equal(t, byte(0x04), g.Read(LCDSAddr)&0x04)
+		assert.Equal(t, interrupt.LCD_STATFlag, irq.IF)
+
+		// LY is read only
+		g.Write(LYAddr, 0)
+		assert.Equal(t, byte(10), g.Read(LYAddr))
+	})
+}
+
+func TestGPU_drawSpriteLine(t *testing.T) {
+	t.Run("10 sprites per line", func(t *testing.T) {
+		g, b, _ := setupGPU(0x93)
+		for i := 0; i < 11; i++ {
+			writeOAM(b, i, 0, i*10, 3, 0)
+		}
+		line := renderLine(g, 0)
+		assert.Equal(t, palette[3], line[90])
+		assert.Equal(t, palette[0], line[100])
+		assert.Equal(t, SPRITE_PER_LINE, len(g.lineSprites))
+
+		// sprites on other lines are not counted
+		writeOAM(b, 0, 20, 0, 3, 0)
+		line = renderLine(g, 0)
+		assert.Equal(t, palette[0], line[0])
+		assert.Equal(t, palette[3], line[100])
+	})
+
+	t.Run("X priority", func(t *testing.T) {
+		g, b, _ := setupGPU(0x93)
+		writeOAM(b, 0, 0, 14, 3, 0)
+		writeOAM(b, 1, 0, 10, 1, 0)
+		// same X, earlier OAM is over
+		writeOAM(b, 2, 0, 30, 2, 0)
+		writeOAM(b, 3, 0, 30, 3, 0)
+		line := renderLine(g, 0)
+		assert.Equal(t, palette[1], line[14])
+		assert.Equal(t, palette[1], line[17])
+		assert.Equal(t, palette[3], line[18])
+		assert.Equal(t, palette[2], line[30])
+	})
+
+	t.Run("transparent", func(t *testing.T) {
+		g, b, _ := setupGPU(0x93)
+		// upper half of tile 1 is color 0
+		for row := 0; row < 4; row++ {
+			b.WriteByte(types.Addr(0x8010+row*2), 0)
+		}
+		writeOAM(b, 0, 0, 10, 1, 0)
+		writeOAM(b, 1, 0, 10, 2, 0)
+		assert.Equal(t, palette[2], renderLine(g, 0)[10])
+		assert.Equal(t, palette[1], renderLine(g, 4)[10])
+	})
+
+	t.Run("8x16", func(t *testing.T) {
+		g, b, _ := setupGPU(0x97)
+		// tile 3 is top, ignoring bit 0 of tile 2
+		writeOAM(b, 0, 0, 0, 3, 0)
+		assert.Equal(t, palette[2], renderLine(g, 0)[0])
+		assert.Equal(t, palette[3], renderLine(g, 8)[0])
+		assert.Equal(t, palette[3], renderLine(g, 15)[0])
+		assert.Equal(t, palette[0], renderLine(g, 16)[0])
+
+		// Y flip swaps tiles
+		writeOAM(b, 0, 0, 0, 2, 0x40)
+		assert.Equal(t, palette[3], renderLine(g, 0)[0])
+		assert.Equal(t, palette[2], renderLine(g, 15)[0])
+	})
+
+	t.Run("flip", func(t *testing.T) {
+		g, b, _ := setupGPU(0x93)
+		// left half of tile 1 is color 0 at row 0
+		b.WriteByte(0x8010, 0x0F)
+		b.WriteByte(0x8011, 0x00)
+		writeOAM(b, 0, 0, 0, 1, 0)
+		line := renderLine(g, 0)
+		assert.Equal(t, palette[0], line[0])
+		assert.Equal(t, palette[1], line[7])
+
+		writeOAM(b, 0, 0, 0, 1, 0x20)
+		line = renderLine(g, 0)
+		assert.Equal(t, palette[1], line[0])
+		assert.Equal(t, palette[0], line[7])
+
+		writeOAM(b, 0, -7, 0, 1, 0x40)
+		assert.Equal(t, palette[1], renderLine(g, 0)[7])
+	})
+
+	t.Run("BG priority", func(t *testing.T) {
+		g, b, _ := setupGPU(0x93)
+		// BG tile 2 on left 8 pixels
+		b.WriteByte(0x9800, 2)
+		writeOAM(b, 0, 0, 4, 3, 0x80)
+		line := renderLine(g, 0)
+		assert.Equal(t, palette[2], line[4])
+		assert.Equal(t, palette[2], line[7])
+		assert.Equal(t, palette[3], line[8])
+
+		// sprite behind BG still hides sprites of lower priority
+		writeOAM(b, 1, 0, 6, 1, 0)
+		line = renderLine(g, 0)
+		assert.Equal(t, palette[2], line[7])
+		assert.Equal(t, palette[3], line[11])
+		assert.Equal(t, palette[1], line[12])
+	})
+
+	t.Run("OBP1", func(t *testing.T) {
+		g, b, _ := setupGPU(0x93)
+		writeOAM(b, 0, 0, 0, 1, 0x10)
+		assert.Equal(t, palette[2], renderLine(g, 0)[0])
+	})
+
+	t.Run("OBJ disabled", func(t *testing.T) {
+		g, b, _ := setupGPU(0x91)
+		writeOAM(b, 0, 0, 0, 3, 0)
+		assert.Equal(t, palette[0], renderLine(g, 0)[0])
+	})
+}
+
+func TestGPU_spritePenalty(t *testing.T) {
+	tests := []struct {
+		name string
+		scx  byte
+		xs   []int
+		want uint
+	}{
+		{"no sprite", 0, nil, 0},
+		{"aligned", 0, []int{0}, 11},
+		{"same tile", 0, []int{0, 0}, 17},
+		{"X=0", 0, []int{-8}, 11},
+		{"SCX", 3, []int{0}, 8},
+		{"right edge", 0, []int{159}, 6},
+		{"off screen", 0, []int{160}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, b, _ := setupGPU(0x93)
+			g.Write(SCXAddr, tt.scx)
+			for i, x := range tt.xs {
+				writeOAM(b, i, 0, x, 1, 0)
 			}
+			renderLine(g, 0)
+			assert.Equal(t, tt.want, g.spritePenalty())
 		})
-		t.Run("tile data 1", func(t *testing.T) {
-			g := setupGPU2()
-			g.LCDC.Data = 0x10 // tile data starts 0x8000
-			g.loadTile()
-
-			for i := 0; i < 255; i++ {
-				if i%2 == 0 {
-					assert.Equal(t, colors2, g.tiles[i].Data)
+	}
+}
+
+func TestGPU_drawWinLine(t *testing.T) {
+	t.Run("from WX-7", func(t *testing.T) {
+		g, _ := setupGPUWindow()
+		g.Write(WXAddr, 17)
+		g.Write(WYAddr, 5)
+		runFrame(g, nil)
+
+		assert.Equal(t, palette[0], g.imageData[50][4])
+		assert.Equal(t, palette[0], g.imageData[9][5])
+		assert.Equal(t, palette[1], g.imageData[10][5])
+		assert.Equal(t, palette[2], g.imageData[10][13])
+	})
+
+	t.Run("line counter", func(t *testing.T) {
+		g, _ := setupGPUWindow()
+		g.Write(WXAddr, 7)
+		// Window is disabled in line 4-9
+		runFrame(g, func(ly byte) {
+			switch ly {
+			case 4:
+				g.Write(LCDCAddr, 0xD1)
+			case 10:
+				g.Write(LCDCAddr, 0xF1)
+			}
+		})
+
+		assert.Equal(t, palette[1], g.imageData[0][3])
+		assert.Equal(t, palette[0], g.imageData[0][4])
+		// line 10 is Window line 4
+		assert.Equal(t, palette[1], g.imageData[0][13])
+		assert.Equal(t, palette[2], g.imageData[0][14])
+		assert.Equal(t, 0, g.winLine)
+	})
+
+	t.Run("WY", func(t *testing.T) {
+		g, _ := setupGPUWindow()
+		g.Write(WXAddr, 7)
+		g.Write(WYAddr, 100)
+		// WY passed LY already
+		runFrame(g, func(ly byte) {
+			if ly == 60 {
+				g.Write(WYAddr, 50)
+			}
+		})
+		assert.Equal(t, palette[0], g.imageData[0][70])
+
+		runFrame(g, nil)
+		assert.Equal(t, palette[0], g.imageData[0][49])
+		assert.Equal(t, palette[1], g.imageData[0][50])
+		assert.Equal(t, palette[2], g.imageData[0][58])
+
+		// WY is not checked again in the frame
+		runFrame(g, func(ly byte) {
+			if ly == 60 {
+				g.Write(WYAddr, 0)
+			}
+		})
+		assert.Equal(t, palette[1], g.imageData[0][50])
+		assert.Equal(t, palette[2], g.imageData[0][60])
+	})
+
+	t.Run("WX<7", func(t *testing.T) {
+		g, b := setupGPUWindow()
+		// left half of tile 1 is color 0
+		for row := 0; row < 8; row++ {
+			b.WriteByte(types.Addr(0x8010+row*2), 0x0F)
+		}
+		g.Write(WXAddr, 3)
+		runFrame(g, nil)
+		assert.Equal(t, palette[1], g.imageData[0][0])
+		assert.Equal(t, palette[1], g.imageData[3][0])
+		assert.Equal(t, palette[0], g.imageData[4][0])
+	})
+
+	t.Run("WX=166", func(t *testing.T) {
+		g, _ := setupGPUWindow()
+		g.Write(WXAddr, 166)
+		runFrame(g, func(ly byte) {
+			if ly == 1 {
+				g.Write(WXAddr, 167)
+			}
+		})
+		assert.Equal(t, palette[0], g.imageData[158][0])
+		assert.Equal(t, palette[1], g.imageData[159][0])
+		// next line is entirely Window
+		assert.Equal(t, palette[1], g.imageData[0][1])
+		assert.Equal(t, palette[1], g.imageData[159][1])
+		assert.Equal(t, palette[0], g.imageData[0][2])
+	})
+
+	t.Run("WX>166", func(t *testing.T) {
+		g, _ := setupGPUWindow()
+		g.Write(WXAddr, 167)
+		runFrame(g, func(ly byte) {
+			if ly == 8 {
+				g.Write(WXAddr, 7)
+			}
+		})
+		assert.Equal(t, palette[0], g.imageData[159][7])
+		// counter is not incremented while Window is hidden
+		assert.Equal(t, palette[1], g.imageData[0][8])
+		assert.Equal(t, palette[1], g.imageData[0][15])
+		assert.Equal(t, palette[2], g.imageData[0][16])
+	})
+}
+
+func TestGPU_FIFORenderer(t *testing.T) {
+	t.Run("same as scanline renderer", func(t *testing.T) {
+		scanline := setupGPUScene(ScanlineRenderer)
+		fifo := setupGPUScene(FIFORenderer)
+		runFrame(scanline, nil)
+		runFrame(fifo, nil)
+		assertSameImage(t, scanline, fifo)
+	})
+
+	t.Run("WX=166 same as scanline renderer", func(t *testing.T) {
+		scanline := setupGPUScene(ScanlineRenderer)
+		fifo := setupGPUScene(FIFORenderer)
+		for _, g := range []*GPU{scanline, fifo} {
+			g.Write(WYAddr, 0)
+			// WX=166 in every 3 lines, Window is hidden in the other lines
+			runFrame(g, func(ly byte) {
+				if ly%3 == 0 {
+					g.Write(WXAddr, 166)
 				} else {
-					assert.Equal(t, colors1, g.tiles[i].Data)
+					g.Write(WXAddr, 167)
 				}
+			})
+		}
+		assertSameImage(t, scanline, fifo)
+
+		// line 1 is entirely Window
+		hidden := setupGPUScene(FIFORenderer)
+		hidden.Write(WXAddr, 167)
+		runFrame(hidden, nil)
+		differ := 0
+		for x := 0; x < SCREEN_WIDTH; x++ {
+			if fifo.imageData[x][1] != hidden.imageData[x][1] {
+				differ++
+			}
+		}
+		assert.Greater(t, differ, SCREEN_WIDTH/2)
+	})
+
+	t.Run("pixel transfer length", func(t *testing.T) {
+		for scx := byte(0); scx < 8; scx++ {
+			g, _, _ := setupGPU(0x91)
+			g.SetRenderer(FIFORenderer)
+			g.Write(SCXAddr, scx)
+			g.Step(OAMScanCycles)
+			assert.Equal(t, Mode_TransferringData, g.LCDS.Mode())
+			g.Step(TransferCycles + uint(scx) - 1)
+			assert.Equal(t, Mode_TransferringData, g.LCDS.Mode(), "SCX=%d", scx)
+			g.Step(1)
+			assert.Equal(t, Mode_HBlank, g.LCDS.Mode(), "SCX=%d", scx)
+		}
+	})
+
+	t.Run("sprites extend pixel transfer", func(t *testing.T) {
+		tests := []struct {
+			name string
+			xs   []int
+		}{
+			{"aligned", []int{40, 80}},
+			{"not aligned", []int{43, 86}},
+			{"same x", []int{40, 40}},
+			{"left edge", []int{-3}},
+			{"hidden in left edge", []int{-7, 20}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				g, b, _ := setupGPU(0x93)
+				g.SetRenderer(FIFORenderer)
+				for i, x := range tt.xs {
+					writeOAM(b, i, 0, x, 1, 0)
+				}
+				g.Step(OAMScanCycles)
+				var dots uint
+				for ; g.LCDS.Mode() == Mode_TransferringData; g.Step(1) {
+					dots++
+				}
+				assert.Equal(t, TransferCycles+g.spritePenalty(), dots)
+			})
+		}
+	})
+
+	t.Run("BGP in the middle of line", func(t *testing.T) {
+		for _, r := range []Renderer{ScanlineRenderer, FIFORenderer} {
+			g, _, _ := setupGPU(0x91)
+			g.SetRenderer(r)
+			// first pixel is pushed after 2 fetches
+			g.Step(OAMScanCycles + fetchDelay + 6 + 80)
+			g.Write(BGPAddr, 0xE7)
+			g.Step(CyclePerLine)
+
+			if r == FIFORenderer {
+				assert.Equal(t, palette[0], g.imageData[79][0])
+				assert.Equal(t, palette[3], g.imageData[80][0])
+			} else {
+				assert.Equal(t, palette[3], g.imageData[0][0])
+			}
+		}
+	})
+
+	t.Run("SCX in the middle of line", func(t *testing.T) {
+		g, b, _ := setupGPU(0x91)
+		g.SetRenderer(FIFORenderer)
+		// BG map column 20 is tile 3
+		b.WriteByte(0x9800+20, 3)
+		g.Step(OAMScanCycles + fetchDelay + 6 + 80)
+		g.Write(SCXAddr, 40)
+		g.Step(CyclePerLine)
+
+		// the next tile to column 10 is column 10+5
+		assert.Equal(t, palette[0], g.imageData[119][0])
+		assert.Equal(t, palette[3], g.imageData[120][0])
+		assert.Equal(t, palette[3], g.imageData[127][0])
+		assert.Equal(t, palette[0], g.imageData[128][0])
+	})
+
+	t.Run("BG disabled", func(t *testing.T) {
+		g, b, _ := setupGPU(0x90)
+		g.SetRenderer(FIFORenderer)
+		b.WriteByte(0x9800, 3)
+		g.Step(CyclePerLine)
+		assert.Equal(t, palette[0], g.imageData[0][0])
+	})
+}
+
+func TestGPU_CGB(t *testing.T) {
+	white := color.RGBA{255, 255, 255, 255}
+	red := color.RGBA{255, 0, 0, 255}
+	green := color.RGBA{0, 255, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+
+	t.Run("palette RAM", func(t *testing.T) {
+		g, _, _ := setupGPUCGB(ScanlineRenderer)
+		assert.Equal(t, byte(0xC0), g.Read(BCPSAddr), "auto increment wraps")
+		g.Write(BCPSAddr, 0x02)
+		assert.Equal(t, byte(0x1F), g.Read(BCPDAddr))
+		g.Write(BCPDAddr, 0xE0)
+		assert.Equal(t, byte(0x42), g.Read(BCPSAddr), "no auto increment")
+		assert.Equal(t, byte(0xE0), g.Read(BCPDAddr))
+		assert.Equal(t, color.RGBA{0, 57, 0, 255}, g.palette.GetCGBPalette(LightGray, 0))
+
+		g.Write(OCPSAddr, 0x8F)
+		assert.Equal(t, byte(0x7C), g.Read(OCPDAddr))
+		assert.Equal(t, blue, g.palette.GetCGBObjPalette(Black, 1))
+	})
+
+	for _, r := range []Renderer{ScanlineRenderer, FIFORenderer} {
+		t.Run("BG map attributes", func(t *testing.T) {
+			tests := []struct {
+				name string
+				attr byte
+				// pixel of color 2
+				x, y int
+				want color.RGBA
+			}{
+				{"bank 0", 0x00, 0, 0, red},
+				{"bank 1", 0x08, 0, 0, green},
+				{"palette", 0x0B, 0, 0, green},
+				{"X flip", 0x28, 7, 0, green},
+				{"Y flip", 0x48, 0, 7, green},
+				{"XY flip", 0x68, 7, 7, green},
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					g, _, vram := setupGPUCGB(r)
+					vram.Buf[0x1800] = 1
+					vram.Buf[VRAMBankSize+0x1800] = tt.attr
+					writeCGBPalette(g, BCPSAddr, BCPDAddr, 3, 0x7FFF, 0x001F, 0x03E0, 0x7C00)
+					runFrame(g, nil)
+
+					assert.Equal(t, tt.want, g.imageData[tt.x][tt.y])
+					if tt.attr&0x08 != 0 {
+						assert.Equal(t, white, g.imageData[7-tt.x][tt.y])
+					}
+					// tile index 0 of the next tile
+					assert.Equal(t, white, g.imageData[8][0])
+				})
+			}
+		})
+
+		t.Run("sprites", func(t *testing.T) {
+			tests := []struct {
+				name string
+				lcdc byte
+				// attributes of BG tile of color 1
+				attr    byte
+				objAttr byte
+				want    [3]color.RGBA
+			}{
+				{"OAM order", 0x93, 0x00, 0x08, [3]color.RGBA{blue, green, green}},
+				{"OBJ priority", 0x93, 0x00, 0x88, [3]color.RGBA{blue, green, red}},
+				{"BG priority", 0x93, 0x80, 0x08, [3]color.RGBA{blue, green, red}},
+				{"BG master priority", 0x92, 0x80, 0x88, [3]color.RGBA{blue, green, green}},
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					g, b, vram := setupGPUCGB(r)
+					g.Write(LCDCAddr, tt.lcdc)
+					// BG of color 1 from x=24
+					vram.Buf[0x1803] = 1
+					vram.Buf[VRAMBankSize+0x1803] = tt.attr
+					writeOAM(b, 0, 0, 20, 2, tt.objAttr)
+					writeOAM(b, 1, 0, 16, 2, 0x09)
+					runFrame(g, nil)
+
+					for i, x := range []int{16, 20, 24} {
+						assert.Equal(t, tt.want[i], g.imageData[x][0], "x=%d", x)
+					}
+				})
 			}
 		})
+	}
+}
+
+func TestGPU_HDMA(t *testing.T) {
+	setup := func() (*GPU, *mock.MockBus) {
+		g, b, _ := setupGPUCGB(ScanlineRenderer)
+		for i := 0; i < 0x100; i++ {
+			b.WriteByte(types.Addr(0xC000+i), byte(i))
+		}
+		g.Write(HDMA1Addr, 0xC0)
+		g.Write(HDMA2Addr, 0x0F)
+		g.Write(HDMA3Addr, 0xE1)
+		g.Write(HDMA4Addr, 0x0F)
+		return g, b
+	}
+	// copied checks blocks are copied from 0xC000 to 0x8100
+	copied := func(t *testing.T, b *mock.MockBus, blocks int) {
+		t.Helper()
+		for i := 0; i < 0x40; i++ {
+			want := byte(0)
+			if i < blocks*16 {
+				want = byte(i)
+			}
+			if !assert.Equal(t, want, b.ReadByte(types.Addr(0x8100+i)), "0x%04X", 0x8100+i) {
+				return
+			}
+		}
+	}
+
+	t.Run("general purpose", func(t *testing.T) {
+		g, b := setup()
+		g.Write(HDMA5Addr, 0x01)
+		assert.Equal(t, uint(16), g.TransferHDMA())
+		copied(t, b, 2)
+		assert.Equal(t, byte(0xFF), g.Read(HDMA5Addr))
+		assert.Equal(t, uint(0), g.TransferHDMA())
+
+		// addresses are advanced
+		g.Write(HDMA5Addr, 0x00)
+		g.TransferHDMA()
+		copied(t, b, 3)
+	})
+
+	t.Run("HBlank", func(t *testing.T) {
+		g, b := setup()
+		g.Step(CyclePerLine)
+		g.Write(HDMA5Addr, 0x82)
+		assert.Equal(t, byte(0x02), g.Read(HDMA5Addr))
+		assert.Equal(t, uint(0), g.TransferHDMA())
+
+		for i := 1; i <= 3; i++ {
+			for g.LCDS.Mode() != Mode_HBlank {
+				g.Step(1)
+			}
+			assert.Equal(t, uint(8), g.TransferHDMA())
+			assert.Equal(t, uint(0), g.TransferHDMA(), "a block per HBlank")
+			copied(t, b, i)
+			g.Step(CyclePerLine - OAMScanCycles - TransferCycles)
+		}
+		assert.Equal(t, byte(0xFF), g.Read(HDMA5Addr))
+		for ; g.Scroll.LY != 0; g.Step(1) {
+			assert.Equal(t, uint(0), g.TransferHDMA())
+		}
+	})
+
+	t.Run("HBlank cancel", func(t *testing.T) {
+		g, b := setup()
+		g.Write(HDMA5Addr, 0x83)
+		g.Step(CyclePerLine * 2)
+		g.TransferHDMA()
+		g.Write(HDMA5Addr, 0x00)
+		assert.Equal(t, byte(0x82), g.Read(HDMA5Addr))
+		g.Step(CyclePerLine * 2)
+		assert.Equal(t, uint(0), g.TransferHDMA())
+		copied(t, b, 1)
+	})
+
+	t.Run("HBlank while LCD off", func(t *testing.T) {
+		g, b := setup()
+		g.Write(LCDCAddr, 0x00)
+		g.Write(HDMA5Addr, 0x81)
+		assert.Equal(t, uint(8), g.TransferHDMA())
+		copied(t, b, 1)
+		assert.Equal(t, byte(0x00), g.Read(HDMA5Addr))
 	})
 }
@@ -0,0 +1,128 @@
+package gpu
+
+import (
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/state"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/types"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/util"
+)
+
+// bytes copied by a block of VRAM DMA
+const hdmaBlockSize = 16
+
+// M-cycles CPU is stopped per block
+const hdmaBlockCycles = 8
+
+// VRAM DMA of CGB, copies blocks of 16 bytes to VRAM
+// General purpose DMA copies all blocks at once, HBlank DMA copies a block in each HBlank
+// @see https://gbdev.io/pandocs/CGB_Registers.html#lcd-vram-dma-transfers
+type hdma struct {
+	// FF51-FF52, lower 4 bits are ignored
+	src types.Addr
+	// FF53-FF54, offset in VRAM, lower 4 bits are ignored
+	dst types.Addr
+	// remaining blocks - 1, bit 0-6 of FF55
+	length byte
+	// HBlank DMA is in progress
+	active bool
+	// blocks to be copied by the next TransferHDMA
+	requested uint
+}
+
+func newHDMA() hdma {
+	return hdma{length: 0x7F}
+}
+
+func (h *hdma) read(addr types.Addr) byte {
+	if addr != HDMA5Addr {
+		// write only
+		return 0xFF
+	}
+	// bit 7 is 0 while HBlank DMA is in progress, FF when finished
+	if h.active {
+		return h.length
+	}
+	return 0x80 | h.length
+}
+
+func (h *hdma) write(addr types.Addr, value byte) {
+	switch addr {
+	case HDMA1Addr:
+		h.src = util.Byte2Addr(value, util.ExtractLower(h.src))
+	case HDMA2Addr:
+		h.src = util.Byte2Addr(util.ExtractUpper(h.src), value&0xF0)
+	case HDMA3Addr:
+		h.dst = util.Byte2Addr(value&0x1F, util.ExtractLower(h.dst))
+	case HDMA4Addr:
+		h.dst = util.Byte2Addr(util.ExtractUpper(h.dst), value&0xF0)
+	case HDMA5Addr:
+		// bit 7 cleared stops HBlank DMA, and the remaining length is kept
+		if h.active && value&0x80 == 0 {
+			h.active = false
+			return
+		}
+		h.length = value & 0x7F
+		if value&0x80 == 0 {
+			h.requested = uint(h.length) + 1
+			return
+		}
+		h.active = true
+	}
+}
+
+// writeHDMA starts DMA, a block is copied immediately if HBlank DMA is started while LCD is off
+func (g *GPU) writeHDMA(addr types.Addr, value byte) {
+	g.hdma.write(addr, value)
+	if addr == HDMA5Addr && g.hdma.active && !g.LCDC.LCDPPUEnable() {
+		g.hdma.requested = 1
+	}
+}
+
+// requestHBlankDMA is called when HBlank starts
+func (g *GPU) requestHBlankDMA() {
+	if g.hdma.active {
+		g.hdma.requested = 1
+	}
+}
+
+// TransferHDMA copies requested blocks, and returns M-cycles CPU is stopped
+func (g *GPU) TransferHDMA() uint {
+	h := &g.hdma
+	blocks := h.requested
+	h.requested = 0
+	for i := uint(0); i < blocks; i++ {
+		for b := types.Addr(0); b < hdmaBlockSize; b++ {
+			v := g.bus.ReadByte(h.src + b)
+			g.bus.WriteByte(0x8000+(h.dst+b)&0x1FFF, v)
+		}
+		h.src += hdmaBlockSize
+		h.dst = (h.dst + hdmaBlockSize) & 0x1FF0
+
+		h.length--
+		if h.length == 0xFF {
+			// finished, FF55 reads FF
+			h.length = 0x7F
+			h.active = false
+		}
+	}
+	return blocks * hdmaBlockCycles
+}
+
+func (h *hdma) SaveState(w *state.Writer) {
+	w.Write(uint16(h.src))
+	w.Write(uint16(h.dst))
+	w.Write(h.length)
+	w.Write(h.active)
+	w.Write(uint8(h.requested))
+}
+
+func (h *hdma) LoadState(r *state.Reader) {
+	var src, dst uint16
+	r.Read(&src)
+	r.Read(&dst)
+	h.src, h.dst = types.Addr(src), types.Addr(dst)
+	r.Read(&h.length)
+	r.Read(&h.active)
+	var requested uint8
+	r.Read(&requested)
+	h.requested = uint(requested)
+}
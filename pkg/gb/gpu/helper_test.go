@@ -0,0 +1,169 @@
+package gpu
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/interrupt"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/memory"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/types"
+	"github.com/Teshima-Tatsuya/GoBoy/test/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupGPU sets tiles filled by a color index, tile n has color n
+// BG is tile 0 of color 0
+func setupGPU(lcdc byte) (*GPU, *mock.MockBus, *interrupt.IRQ) {
+	g := New()
+	b := mock.NewMockBus()
+	irq := interrupt.NewIRQ()
+	for tile := 1; tile < 4; tile++ {
+		for row := 0; row < 8; row++ {
+			addr := types.Addr(0x8000 + tile*16 + row*2)
+			b.WriteByte(addr, byte(tile&1)*0xFF)
+			b.WriteByte(addr+1, byte(tile>>1)*0xFF)
+		}
+	}
+	g.Init(b, irq.Request)
+	g.Write(LCDCAddr, lcdc)
+	g.Write(BGPAddr, 0xE4)
+	g.Write(OBP0Addr, 0xE4)
+	g.Write(OBP1Addr, 0x1B)
+	return g, b, irq
+}
+
+// setupGPUWindow sets Window map to tile 1 in row 0, and tile 2 in row 1
+func setupGPUWindow() (*GPU, *mock.MockBus) {
+	// LCD, Window map 0x9C00, Window, tile data 0x8000, BG
+	g, b, _ := setupGPU(0xF1)
+	for x := 0; x < 32; x++ {
+		b.WriteByte(types.Addr(0x9C00+x), 1)
+		b.WriteByte(types.Addr(0x9C20+x), 2)
+	}
+	return g, b
+}
+
+// setupGPUScene sets BG, Window and sprites for comparison of renderers
+func setupGPUScene(r Renderer) *GPU {
+	g, b, _ := setupGPU(0xF3)
+	g.SetRenderer(r)
+	// tile 4 is vertical stripes of color 1 and 2
+	for row := 0; row < 8; row++ {
+		b.WriteByte(types.Addr(0x8040+row*2), 0xAA)
+		b.WriteByte(types.Addr(0x8041+row*2), 0x55)
+	}
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			b.WriteByte(types.Addr(0x9800+y*32+x), byte((x+y)%5))
+			b.WriteByte(types.Addr(0x9C00+y*32+x), byte((x*3+y)%5))
+		}
+	}
+	g.Write(SCXAddr, 3)
+	g.Write(SCYAddr, 5)
+	g.Write(WXAddr, 90)
+	g.Write(WYAddr, 100)
+
+	writeOAM(b, 0, 10, 20, 4, 0)
+	writeOAM(b, 1, 14, 24, 3, 0x10)
+	writeOAM(b, 2, 30, -4, 4, 0x20)
+	writeOAM(b, 3, 50, 40, 3, 0x80)
+	writeOAM(b, 4, 96, 86, 4, 0x40)
+	writeOAM(b, 5, 140, 156, 2, 0)
+	for i := 6; i < 18; i++ {
+		writeOAM(b, i, 70, i*9, byte(i%4+1), 0)
+	}
+	return g
+}
+
+// setupGPUCGB sets CGB mode with palettes of white, red, green and blue
+func setupGPUCGB(r Renderer) (*GPU, *mock.MockBus, *memory.RAM) {
+	g, b, _ := setupGPU(0x93)
+	vram := memory.NewRAM(2 * VRAMBankSize)
+	g.SetCGBMode(vram)
+	g.SetRenderer(r)
+
+	// bank 0 tile 1 is color 1, bank 1 tile 1 has color 2 only at the top left
+	for row := 0; row < 8; row++ {
+		vram.Buf[16+row*2] = 0xFF
+	}
+	vram.Buf[VRAMBankSize+16+1] = 0x80
+	// bank 1 tile 2 is color 3
+	for i := 0; i < 16; i++ {
+		vram.Buf[VRAMBankSize+32+i] = 0xFF
+	}
+
+	for no := byte(0); no < 8; no++ {
+		writeCGBPalette(g, BCPSAddr, BCPDAddr, no, 0x7FFF, 0x001F, 0x03E0, 0x7C00)
+	}
+	writeCGBPalette(g, OCPSAddr, OCPDAddr, 0, 0, 0, 0, 0x03E0)
+	writeCGBPalette(g, OCPSAddr, OCPDAddr, 1, 0, 0, 0, 0x7C00)
+	return g, b, vram
+}
+
+// writeCGBPalette writes colors of palette no by auto increment
+func writeCGBPalette(g *GPU, spec, data types.Addr, no byte, colors ...uint16) {
+	g.Write(spec, 0x80|no*8)
+	for _, c := range colors {
+		g.Write(data, byte(c))
+		g.Write(data, byte(c>>8))
+	}
+}
+
+func writeOAM(b *mock.MockBus, i int, y, x int, tile, attr byte) {
+	addr := OAMSTARTAddr + types.Addr(i*4)
+	b.WriteByte(addr, byte(y+16))
+	b.WriteByte(addr+1, byte(x+8))
+	b.WriteByte(addr+2, tile)
+	b.WriteByte(addr+3, attr)
+}
+
+func renderLine(g *GPU, ly byte) [SCREEN_WIDTH]color.RGBA {
+	g.Scroll.LY = ly
+	g.loadTile()
+	g.scanOAM()
+	g.drawBGLine()
+	g.drawSpriteLine()
+
+	var line [SCREEN_WIDTH]color.RGBA
+	for x := 0; x < SCREEN_WIDTH; x++ {
+		line[x] = g.imageData[x][ly]
+	}
+	return line
+}
+
+// runFrame steps a frame from the start of LY=0, before is called at the start of each line
+func runFrame(g *GPU, before func(ly byte)) {
+	for i := 0; i < LinePerFrame; i++ {
+		if before != nil {
+			before(g.Scroll.LY)
+		}
+		g.Step(CyclePerLine)
+	}
+}
+
+// stepSTAT steps a dot at a time, and returns dots STAT interrupt is requested at
+func stepSTAT(g *GPU, irq *interrupt.IRQ, cycles uint, onIRQ func()) []uint {
+	var got []uint
+	for dot := uint(1); dot <= cycles; dot++ {
+		g.Step(1)
+		if irq.IF&interrupt.LCD_STATFlag != 0 {
+			got = append(got, dot)
+			irq.IF = 0
+			if onIRQ != nil {
+				onIRQ()
+			}
+		}
+	}
+	return got
+}
+
+// assertSameImage compares images of GPUs pixel by pixel, and reports the first difference
+func assertSameImage(t *testing.T, expected, actual *GPU) {
+	for y := 0; y < SCREEN_HEIGHT; y++ {
+		for x := 0; x < SCREEN_WIDTH; x++ {
+			if !assert.Equal(t, expected.imageData[x][y], actual.imageData[x][y], "x=%d y=%d", x, y) {
+				return
+			}
+		}
+	}
+}
@@ -3,6 +3,7 @@ package gpu
 import (
 	"image/color"
 
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/state"
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/types"
 )
 
@@ -23,18 +24,25 @@ type Palette struct {
 
 	// CGB Only
 
-	// FF68
+	// FF68, bit 7 is auto increment and bit 0-5 is address of bgRAM
 	BCPS byte
-	// FF69
-	BCPD byte
-	// FF6A
+	// FF6A, same as BCPS for objRAM
 	OCPS byte
-	// FF6B
-	OCPD byte
+
+	// palette RAM accessed by BCPD(FF69) and OCPD(FF6B)
+	// 8 palettes of 4 colors, each color is little endian RGB555
+	// @see https://gbdev.io/pandocs/Palettes.html#lcd-color-palettes-cgb-only
+	bgRAM  [64]byte
+	objRAM [64]byte
 }
 
 func NewPalette() *Palette {
-	return &Palette{}
+	p := &Palette{}
+	// boot ROM of CGB makes BG white
+	for i := range p.bgRAM {
+		p.bgRAM[i] = 0xFF
+	}
+	return p
 }
 
 func (p *Palette) GetPalette(idx Color) color.RGBA {
@@ -61,6 +69,35 @@ func (p *Palette) GetObjPalette(idx Color, obp uint) color.RGBA {
 	return palette[c]
 }
 
+// GetCGBPalette returns color of BG palette no in CGB mode
+func (p *Palette) GetCGBPalette(idx Color, no byte) color.RGBA {
+	i := no*8 + byte(idx)*2
+	return rgb555(p.bgRAM[i], p.bgRAM[i+1])
+}
+
+// GetCGBObjPalette returns color of OBJ palette no in CGB mode
+func (p *Palette) GetCGBObjPalette(idx Color, no byte) color.RGBA {
+	i := no*8 + byte(idx)*2
+	return rgb555(p.objRAM[i], p.objRAM[i+1])
+}
+
+// rgb555 scales 5 bits of each color to 8 bits
+func rgb555(low, high byte) color.RGBA {
+	c := uint16(high)<<8 | uint16(low)
+	r := byte(c & 0x1F)
+	g := byte(c >> 5 & 0x1F)
+	b := byte(c >> 10 & 0x1F)
+	return color.RGBA{r<<3 | r>>2, g<<3 | g>>2, b<<3 | b>>2, 255}
+}
+
+// writePaletteRAM writes data at the address of spec, and increments the address if bit 7 is set
+func writePaletteRAM(ram *[64]byte, spec *byte, value byte) {
+	ram[*spec&0x3F] = value
+	if *spec&0x80 != 0 {
+		*spec = *spec&0x80 | (*spec+1)&0x3F
+	}
+}
+
 func (p *Palette) Read(addr types.Addr) byte {
 	switch addr {
 	case BGPAddr:
@@ -70,13 +107,14 @@ func (p *Palette) Read(addr types.Addr) byte {
 	case OBP1Addr:
 		return p.OBP1
 	case BCPSAddr:
-		return p.BCPS
+		// bit 6 is unused
+		return p.BCPS | 0x40
 	case BCPDAddr:
-		return p.BCPD
+		return p.bgRAM[p.BCPS&0x3F]
 	case OCPSAddr:
-		return p.OCPS
+		return p.OCPS | 0x40
 	case OCPDAddr:
-		return p.OCPD
+		return p.objRAM[p.OCPS&0x3F]
 	default:
 		panic("Palette Read")
 	}
@@ -93,12 +131,26 @@ func (p *Palette) Write(addr types.Addr, value byte) {
 	case BCPSAddr:
 		p.BCPS = value
 	case BCPDAddr:
-		p.BCPD = value
+		writePaletteRAM(&p.bgRAM, &p.BCPS, value)
 	case OCPSAddr:
 		p.OCPS = value
 	case OCPDAddr:
-		p.OCPD = value
+		writePaletteRAM(&p.objRAM, &p.OCPS, value)
 	default:
 		panic("Palette Write")
 	}
 }
+
+func (p *Palette) SaveState(w *state.Writer) {
+	w.Write([]byte{p.BGP, p.OBP0, p.OBP1, p.BCPS, p.OCPS})
+	w.Write(p.bgRAM)
+	w.Write(p.objRAM)
+}
+
+func (p *Palette) LoadState(r *state.Reader) {
+	for _, v := range []*byte{&p.BGP, &p.OBP0, &p.OBP1, &p.BCPS, &p.OCPS} {
+		r.Read(v)
+	}
+	r.Read(&p.bgRAM)
+	r.Read(&p.objRAM)
+}
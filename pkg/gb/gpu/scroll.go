@@ -1,6 +1,9 @@
 package gpu
 
-import "github.com/Teshima-Tatsuya/GoBoy/pkg/types"
+import (
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/state"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/types"
+)
 
 type Scroll struct {
 	// FF42, FF43
@@ -75,7 +78,7 @@ func (s *Scroll) Write(addr types.Addr, value byte) {
 	case SCXAddr:
 		s.SCX = value
 	case LYAddr:
-		s.LY = value
+		// LY is read only
 	case LYCAddr:
 		s.LYC = value
 	case WXAddr:
@@ -86,3 +89,13 @@ func (s *Scroll) Write(addr types.Addr, value byte) {
 		panic("Scroll Write")
 	}
 }
+
+func (s *Scroll) SaveState(w *state.Writer) {
+	w.Write([]byte{s.SCY, s.SCX, s.LY, s.LYC, s.WX, s.WY})
+}
+
+func (s *Scroll) LoadState(r *state.Reader) {
+	for _, v := range []*byte{&s.SCY, &s.SCX, &s.LY, &s.LYC, &s.WX, &s.WY} {
+		r.Read(v)
+	}
+}
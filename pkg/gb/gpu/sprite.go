@@ -2,27 +2,48 @@ package gpu
 
 import "github.com/Teshima-Tatsuya/GoBoy/pkg/util"
 
+// @see https://gbdev.io/pandocs/OAM.html
 type Sprite struct {
+	// position in OAM, y is the bottom of 16 pixels tall sprite and x is the right
 	y, x, tileIdx, attr byte
 }
 
 func NewSprite(bytes4 []byte) *Sprite {
 	s := &Sprite{}
 
-	s.y = bytes4[0] - 16
-	s.x = bytes4[1] - 8
+	s.y = bytes4[0]
+	s.x = bytes4[1]
 	s.tileIdx = bytes4[2]
 	s.attr = bytes4[3]
 
 	return s
 }
 
+// Y is the top of the sprite on screen
+func (s *Sprite) Y() int {
+	return int(s.y) - 16
+}
+
+// X is the left of the sprite on screen
+func (s *Sprite) X() int {
+	return int(s.x) - 8
+}
+
 func (s *Sprite) TileIdx() byte {
 	return s.tileIdx
 }
 
+func (s *Sprite) Bytes() []byte {
+	return []byte{s.y, s.x, s.tileIdx, s.attr}
+}
+
 // attr methods
 
+// BG and Window colors 1-3 are drawn over the sprite
+func (s *Sprite) BGPriority() bool {
+	return util.Bit(s.attr, 7) == 1
+}
+
 func (s *Sprite) YFlip() bool {
 	return util.Bit(s.attr, 6) == 1
 }
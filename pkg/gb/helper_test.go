@@ -0,0 +1,40 @@
+package gb
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/cpu"
+)
+
+func setup(romData []byte) *GB {
+	return NewGB(romData)
+}
+
+// setupFile makes GB of ROM file in test/rom
+func setupFile(t *testing.T, file string) *GB {
+	romData, err := ioutil.ReadFile("../../test/rom/" + file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return setup(romData)
+}
+
+// setupROM makes GB of ROM with CGB flag, which runs program from 0x0100
+func setupROM(cgbFlag byte, program ...byte) *GB {
+	romData := make([]byte, 0x8000)
+	romData[0x0143] = cgbFlag
+	copy(romData[0x0100:], program)
+	return setup(romData)
+}
+
+// setupInterrupt makes GB running program with A=0, and interrupts of flag requested and enabled
+func setupInterrupt(flag byte, program ...byte) *GB {
+	gb := setupROM(0x00, program...)
+	gb.cpu.Reg.R[cpu.A] = 0
+	gb.irq.IME = false
+	gb.irq.IE = flag
+	gb.irq.IF = flag
+	return gb
+}
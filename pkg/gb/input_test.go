@@ -0,0 +1,28 @@
+package gb
+
+import (
+	"testing"
+
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/pad"
+	"github.com/Teshima-Tatsuya/GoBoy/test/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGB_InputProvider(t *testing.T) {
+	gb := setupFile(t, "helloworld/hello.gb")
+	gb.SetInputProvider(mock.NewMockInput(pad.Start|pad.Down, pad.A))
+	gb.Step()
+
+	// select buttons
+	gb.bus.WriteByte(0xFF00, 0x10)
+	assert.Equal(t, byte(0xD7), gb.ReadByte(0xFF00))
+	// select directions
+	gb.bus.WriteByte(0xFF00, 0x20)
+	assert.Equal(t, byte(0xE7), gb.ReadByte(0xFF00))
+
+	gb.Step()
+	gb.bus.WriteByte(0xFF00, 0x10)
+	assert.Equal(t, byte(0xDE), gb.ReadByte(0xFF00))
+	gb.bus.WriteByte(0xFF00, 0x20)
+	assert.Equal(t, byte(0xEF), gb.ReadByte(0xFF00))
+}
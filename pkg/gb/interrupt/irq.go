@@ -3,6 +3,7 @@ package interrupt
 import (
 	"fmt"
 
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/state"
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/types"
 )
 
@@ -37,8 +38,9 @@ func NewIRQ() *IRQ {
 	}
 }
 
+// Request sets flags of v, other requested flags are kept
 func (i *IRQ) Request(v byte) {
-	i.Write(IFAddr, v)
+	i.Write(IFAddr, i.IF|v)
 }
 
 // if any irq is enable
@@ -103,3 +105,15 @@ func (i *IRQ) Read(addr types.Addr) byte {
 		panic("Can't Write addr")
 	}
 }
+
+func (i *IRQ) SaveState(w *state.Writer) {
+	w.Write(i.IF)
+	w.Write(i.IE)
+	w.Write(i.IME)
+}
+
+func (i *IRQ) LoadState(r *state.Reader) {
+	r.Read(&i.IF)
+	r.Read(&i.IE)
+	r.Read(&i.IME)
+}
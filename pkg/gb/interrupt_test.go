@@ -0,0 +1,98 @@
+package gb
+
+import (
+	"testing"
+
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/cpu"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/interrupt"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGB_Interrupt(t *testing.T) {
+	t.Run("EI is delayed by an instruction", func(t *testing.T) {
+		// EI, INC A, INC A
+		gb := setupInterrupt(interrupt.VBlankFlag, 0xFB, 0x3C, 0x3C)
+		gb.cpu.Step()
+		assert.False(t, gb.irq.IME)
+
+		gb.cpu.Step()
+		assert.True(t, gb.irq.IME)
+		assert.Equal(t, byte(1), gb.cpu.Reg.R[cpu.A])
+
+		assert.Equal(t, uint(5), gb.cpu.Step())
+		assert.Equal(t, interrupt.VBlankAddr, gb.cpu.Reg.PC)
+		assert.Equal(t, byte(1), gb.cpu.Reg.R[cpu.A])
+	})
+
+	t.Run("EI followed by DI", func(t *testing.T) {
+		// EI, DI, INC A
+		gb := setupInterrupt(interrupt.VBlankFlag, 0xFB, 0xF3, 0x3C)
+		for i := 0; i < 3; i++ {
+			gb.cpu.Step()
+		}
+		assert.False(t, gb.irq.IME)
+		assert.Equal(t, types.Addr(0x0103), gb.cpu.Reg.PC)
+		assert.Equal(t, byte(1), gb.cpu.Reg.R[cpu.A])
+	})
+
+	t.Run("EI with IME=1 does not enable IME in the handler", func(t *testing.T) {
+		// EI, INC A
+		gb := setupInterrupt(interrupt.VBlankFlag, 0xFB, 0x3C)
+		gb.irq.IME = true
+		gb.irq.IF = 0
+		gb.cpu.Step()
+
+		gb.irq.IF = interrupt.VBlankFlag
+		assert.Equal(t, uint(5), gb.cpu.Step())
+		assert.Equal(t, interrupt.VBlankAddr, gb.cpu.Reg.PC)
+
+		// NOP at the vector
+		gb.cpu.Step()
+		assert.False(t, gb.irq.IME)
+	})
+
+	t.Run("HALT bug", func(t *testing.T) {
+		// HALT, INC A, NOP
+		gb := setupInterrupt(interrupt.TimerFlag, 0x76, 0x3C, 0x00)
+		gb.cpu.Step()
+		assert.False(t, gb.cpu.Halt)
+
+		gb.cpu.Step()
+		assert.Equal(t, types.Addr(0x0101), gb.cpu.Reg.PC)
+		gb.cpu.Step()
+		assert.Equal(t, types.Addr(0x0102), gb.cpu.Reg.PC)
+		assert.Equal(t, byte(2), gb.cpu.Reg.R[cpu.A])
+	})
+
+	t.Run("HALT without interrupt pending", func(t *testing.T) {
+		gb := setupInterrupt(0, 0x76, 0x3C, 0x00)
+		gb.cpu.Step()
+		assert.True(t, gb.cpu.Halt)
+	})
+
+	tests := []struct {
+		name   string
+		flag   byte
+		wantPC types.Addr
+		wantIF byte
+	}{
+		// upper byte 0x02 of PC is pushed to IE
+		{"cancelled by IE pushed", interrupt.VBlankFlag, 0x0000, interrupt.VBlankFlag},
+		{"vector by IE pushed", interrupt.VBlankFlag | interrupt.LCD_STATFlag, interrupt.LCD_STATAddr, interrupt.VBlankFlag},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gb := setupInterrupt(tt.flag)
+			gb.irq.IME = true
+			gb.cpu.Reg.SP = 0x0000
+			gb.cpu.Reg.PC = 0x0200
+
+			assert.Equal(t, uint(5), gb.cpu.Step())
+			assert.Equal(t, tt.wantPC, gb.cpu.Reg.PC)
+			assert.Equal(t, tt.wantIF, gb.irq.IF)
+			assert.Equal(t, byte(0x02), gb.irq.IE)
+			assert.False(t, gb.irq.IME)
+		})
+	}
+}
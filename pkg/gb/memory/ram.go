@@ -1,6 +1,9 @@
 package memory
 
-import "github.com/Teshima-Tatsuya/GoBoy/pkg/types"
+import (
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/state"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/types"
+)
 
 type RAM struct {
 	Buf []byte
@@ -21,3 +24,12 @@ func (r *RAM) Read(addr types.Addr) byte {
 func (r *RAM) Write(addr types.Addr, value byte) {
 	r.Buf[addr] = value
 }
+
+func (r *RAM) SaveState(w *state.Writer) {
+	w.Write(r.Buf)
+}
+
+// size of RAM must be same as saved one
+func (r *RAM) LoadState(sr *state.Reader) {
+	sr.Read(r.Buf)
+}
@@ -0,0 +1,180 @@
+package gb
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/cpu"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/gpu"
+	"github.com/stretchr/testify/assert"
+)
+
+const mooneyePath = "../../test/rom/mooneye-gb"
+
+// LD B,B
+const mooneyeBreakpoint = 0x40
+
+// frames to wait for the breakpoint, most ROMs finish in a second
+// but bits_ramg of MBC1 and MBC2 takes about 6 seconds
+const mooneyeFrames = 600
+
+type mooneyeResult string
+
+const (
+	mooneyePass    mooneyeResult = "pass"
+	mooneyeFail    mooneyeResult = "fail"
+	mooneyeTimeout mooneyeResult = "timeout"
+	mooneyePanic   mooneyeResult = "panic"
+)
+
+// ROMs not passed yet, which are skipped without running
+var mooneyeKnownFailures = map[string]bool{
+	"acceptance/add_sp_e_timing.gb":                  true,
+	"acceptance/boot_div-dmgABCmgb.gb":               true,
+	"acceptance/boot_hwio-dmgABCmgb.gb":              true,
+	"acceptance/boot_regs-dmgABC.gb":                 true,
+	"acceptance/call_cc_timing.gb":                   true,
+	"acceptance/call_cc_timing2.gb":                  true,
+	"acceptance/call_timing.gb":                      true,
+	"acceptance/call_timing2.gb":                     true,
+	"acceptance/di_timing-GS.gb":                     true,
+	"acceptance/halt_ime1_timing2-GS.gb":             true,
+	"acceptance/jp_cc_timing.gb":                     true,
+	"acceptance/jp_timing.gb":                        true,
+	"acceptance/ld_hl_sp_e_timing.gb":                true,
+	"acceptance/oam_dma/sources-GS.gb":               true,
+	"acceptance/oam_dma_restart.gb":                  true,
+	"acceptance/oam_dma_start.gb":                    true,
+	"acceptance/oam_dma_timing.gb":                   true,
+	"acceptance/pop_timing.gb":                       true,
+	"acceptance/ppu/hblank_ly_scx_timing-GS.gb":      true,
+	"acceptance/ppu/intr_1_2_timing-GS.gb":           true,
+	"acceptance/ppu/intr_2_0_timing.gb":              true,
+	"acceptance/ppu/intr_2_mode0_timing_sprites.gb":  true,
+	"acceptance/ppu/intr_2_oam_ok_timing.gb":         true,
+	"acceptance/ppu/lcdon_timing-GS.gb":              true,
+	"acceptance/ppu/lcdon_write_timing-GS.gb":        true,
+	"acceptance/ppu/stat_lyc_onoff.gb":               true,
+	"acceptance/push_timing.gb":                      true,
+	"acceptance/ret_cc_timing.gb":                    true,
+	"acceptance/ret_timing.gb":                       true,
+	"acceptance/reti_timing.gb":                      true,
+	"acceptance/rst_timing.gb":                       true,
+	"acceptance/serial/boot_sclk_align-dmgABCmgb.gb": true,
+	"acceptance/timer/rapid_toggle.gb":               true,
+	"acceptance/timer/tima_write_reloading.gb":       true,
+	"acceptance/timer/tma_write_reloading.gb":        true,
+}
+
+// directories for manual tests, other models or tools
+var mooneyeExcludes = []string{"madness", "manual-only", "misc", "utils"}
+
+// suffix is the models the ROM runs, e.g. -GS, -dmgABCmgb
+// @see https://github.com/Gekkio/mooneye-test-suite#test-naming
+var mooneyeModels = regexp.MustCompile(`-([A-Za-z0-9]+)\.gb$`)
+
+// mooneyeROMs finds ROMs for DMG under mooneyePath
+func mooneyeROMs(t *testing.T) []string {
+	var roms []string
+	err := filepath.Walk(mooneyePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(mooneyePath, path)
+		if info.IsDir() {
+			for _, e := range mooneyeExcludes {
+				if rel == e {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".gb" {
+			return nil
+		}
+		if m := mooneyeModels.FindStringSubmatch(path); m != nil {
+			if !strings.Contains(m[1], "G") && !strings.Contains(m[1], "dmgABC") {
+				return nil
+			}
+		}
+		roms = append(roms, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(roms)
+	return roms
+}
+
+// runMooneye runs ROM until LD B,B is executed
+// Registers have fibonacci numbers when the test passed
+func runMooneye(file string) (result mooneyeResult, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = mooneyePanic, fmt.Errorf("%v", r)
+		}
+	}()
+
+	romData, err := ioutil.ReadFile(mooneyePath + "/" + file)
+	if err != nil {
+		return "", err
+	}
+	gb := setup(romData)
+	// FIFO renderer draws a pixel per dot as the hardware does
+	gb.SetRenderer(gpu.FIFORenderer)
+	var got []byte
+	gb.SetBreakpoint(mooneyeBreakpoint, func() {
+		r := gb.Registers()
+		got = []byte{r.R[cpu.B], r.R[cpu.C], r.R[cpu.D], r.R[cpu.E], r.R[cpu.H], r.R[cpu.L]}
+	})
+
+	for i := 0; i < mooneyeFrames && got == nil; i++ {
+		gb.Step()
+	}
+	if got == nil {
+		return mooneyeTimeout, nil
+	}
+
+	if string(got) != string([]byte{3, 5, 8, 13, 21, 34}) {
+		return mooneyeFail, nil
+	}
+	return mooneyePass, nil
+}
+
+func TestGB_mooneye(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs all ROMs of mooneye-test-suite")
+	}
+
+	var report strings.Builder
+	fmt.Fprintf(&report, "\n%-60s %s\n", "ROM", "result")
+
+	for _, file := range mooneyeROMs(t) {
+		if mooneyeKnownFailures[file] {
+			fmt.Fprintf(&report, "%-60s %s\n", file, "skip")
+			t.Run(file, func(t *testing.T) {
+				t.Skip("known failure")
+			})
+			continue
+		}
+
+		result, err := runMooneye(file)
+		fmt.Fprintf(&report, "%-60s %s\n", file, result)
+
+		t.Run(file, func(t *testing.T) {
+			if err != nil && result != mooneyePanic {
+				t.Fatal(err)
+			}
+			assert.Equal(t, mooneyePass, result, "%v", err)
+		})
+	}
+
+	t.Log(report.String())
+}
@@ -1,6 +1,7 @@
 package pad
 
 import (
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/state"
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/types"
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/util"
 )
@@ -77,3 +78,18 @@ func (pad *Pad) Press(button Button) {
 func (pad *Pad) Release(button Button) {
 	pad.state &= ^button
 }
+
+// Set replaces all pressed buttons
+func (pad *Pad) Set(buttons Button) {
+	pad.state = buttons
+}
+
+func (pad *Pad) SaveState(w *state.Writer) {
+	w.Write(pad.p1)
+	w.Write(pad.state)
+}
+
+func (pad *Pad) LoadState(r *state.Reader) {
+	r.Read(&pad.p1)
+	r.Read(&pad.state)
+}
@@ -3,6 +3,8 @@ package serial
 import (
 	"fmt"
 
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/interrupt"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/state"
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/types"
 )
 
@@ -11,9 +13,25 @@ const (
 	SCAddr = 0x02
 )
 
+// a bit is shifted every 128 cycles with internal clock, which is 8192Hz
+// @see https://gbdev.io/pandocs/Serial_Data_Transfer_(Link_Cable).html
+const bitCycles = 128
+
 type Serial struct {
 	SB byte
 	SC byte
+
+	// CGB mode, bit 1 of SC selects the fast clock
+	cgb bool
+
+	requestIRQ func(byte)
+	// receives the byte sent when a transfer is completed
+	output func(byte)
+
+	// byte being sent, and progress of the transfer
+	sending byte
+	bits    byte
+	counter uint16
 }
 
 func NewSerial() *Serial {
@@ -23,12 +41,33 @@ func NewSerial() *Serial {
 	}
 }
 
+// SetCGBMode enables bit 1 of SC
+func (s *Serial) SetCGBMode() {
+	s.cgb = true
+}
+
+// scMask is bits of SC which can be written, the others are read as 1
+func (s *Serial) scMask() byte {
+	if s.cgb {
+		return 0x83
+	}
+	return 0x81
+}
+
+func (s *Serial) SetRequestIRQ(request func(byte)) {
+	s.requestIRQ = request
+}
+
+func (s *Serial) SetOutput(output func(byte)) {
+	s.output = output
+}
+
 func (s *Serial) Read(addr types.Addr) byte {
 	switch {
 	case addr == SBAddr:
 		return s.SB
 	case addr == SCAddr:
-		return s.SC | 0x7E
+		return s.SC | ^s.scMask()
 	default:
 		msg := fmt.Sprintf("Sereal doesn't support addr 0x%02X", addr)
 		panic(msg)
@@ -40,9 +79,64 @@ func (s *Serial) Write(addr types.Addr, value byte) {
 	case addr == SBAddr:
 		s.SB = value
 	case addr == SCAddr:
-		s.SC = value & 0x83
+		s.SC = value & s.scMask()
+		if s.transferring() {
+			s.sending = s.SB
+			s.bits = 0
+			s.counter = 0
+		}
 	default:
 		msg := fmt.Sprintf("Sereal doesn't support addr 0x%02X", addr)
 		panic(msg)
 	}
 }
+
+// transfer with external clock never progresses, because nothing is connected
+func (s *Serial) transferring() bool {
+	return s.SC&0x81 == 0x81
+}
+
+func (s *Serial) Tick(cycle uint) {
+	if !s.transferring() {
+		return
+	}
+
+	s.counter += uint16(cycle)
+	for s.counter >= bitCycles {
+		s.counter -= bitCycles
+		// 1 is received from disconnected cable
+		s.SB = s.SB<<1 | 0x01
+		s.bits++
+		if s.bits == 8 {
+			s.complete()
+			return
+		}
+	}
+}
+
+func (s *Serial) complete() {
+	s.SC &= 0x7F
+	s.counter = 0
+	if s.output != nil {
+		s.output(s.sending)
+	}
+	if s.requestIRQ != nil {
+		s.requestIRQ(interrupt.SerialFlag)
+	}
+}
+
+func (s *Serial) SaveState(w *state.Writer) {
+	w.Write(s.SB)
+	w.Write(s.SC)
+	w.Write(s.sending)
+	w.Write(s.bits)
+	w.Write(s.counter)
+}
+
+func (s *Serial) LoadState(r *state.Reader) {
+	r.Read(&s.SB)
+	r.Read(&s.SC)
+	r.Read(&s.sending)
+	r.Read(&s.bits)
+	r.Read(&s.counter)
+}
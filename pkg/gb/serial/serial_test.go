@@ -0,0 +1,85 @@
+package serial
+
+import (
+	"testing"
+
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/interrupt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSerial_Transfer(t *testing.T) {
+	t.Run("internal clock", func(t *testing.T) {
+		s := NewSerial()
+		var irq byte
+		s.SetRequestIRQ(func(flag byte) {
+			irq |= flag
+		})
+		var sink Sink
+		s.SetOutput(sink.Write)
+
+		s.Write(SBAddr, 'P')
+		s.Write(SCAddr, 0x81)
+		assert.Equal(t, byte(0xFF), s.Read(SCAddr))
+
+		s.Tick(bitCycles*8 - 1)
+		assert.Equal(t, byte(0x00), irq)
+		assert.Equal(t, "", sink.String())
+
+		s.Tick(1)
+		assert.Equal(t, interrupt.SerialFlag, irq)
+		assert.Equal(t, byte(0x7F), s.Read(SCAddr))
+		// received from disconnected cable
+		assert.Equal(t, byte(0xFF), s.Read(SBAddr))
+
+		s.Write(SBAddr, 'a')
+		s.Write(SCAddr, 0x81)
+		s.Tick(bitCycles * 8)
+		s.Write(SBAddr, 's')
+		s.Write(SCAddr, 0x81)
+		for i := 0; i < bitCycles*8; i++ {
+			s.Tick(1)
+		}
+		assert.Equal(t, "Pas", sink.String())
+		assert.True(t, sink.Contains("as"))
+	})
+
+	t.Run("external clock", func(t *testing.T) {
+		s := NewSerial()
+		var sink Sink
+		s.SetOutput(sink.Write)
+
+		s.Write(SBAddr, 'P')
+		s.Write(SCAddr, 0x80)
+		s.Tick(bitCycles * 100)
+		assert.Equal(t, byte(0xFE), s.Read(SCAddr))
+		assert.Equal(t, byte('P'), s.Read(SBAddr))
+		assert.Equal(t, "", sink.String())
+	})
+
+	tests := []struct {
+		name string
+		cgb  bool
+		// SC after 0x02 is written, and values read after 0x02 and 0x00 are written
+		wantSC   byte
+		wantRead byte
+		wantZero byte
+	}{
+		// fast clock of bit 1 exists only in CGB mode, unused bits are read as 1
+		{"DMG", false, 0x00, 0x7E, 0x7E},
+		{"CGB", true, 0x02, 0x7E, 0x7C},
+	}
+	for _, tt := range tests {
+		t.Run("fast clock in "+tt.name, func(t *testing.T) {
+			s := NewSerial()
+			if tt.cgb {
+				s.SetCGBMode()
+			}
+			s.Write(SCAddr, 0x02)
+			assert.Equal(t, tt.wantSC, s.SC)
+			assert.Equal(t, tt.wantRead, s.Read(SCAddr))
+
+			s.Write(SCAddr, 0x00)
+			assert.Equal(t, tt.wantZero, s.Read(SCAddr))
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package serial
+
+import (
+	"bytes"
+)
+
+// Sink collects bytes sent from serial port, e.g. output of test ROMs
+// Write is passed to SetOutput
+type Sink struct {
+	buf bytes.Buffer
+}
+
+func (s *Sink) Write(b byte) {
+	s.buf.WriteByte(b)
+}
+
+func (s *Sink) Bytes() []byte {
+	return s.buf.Bytes()
+}
+
+func (s *Sink) String() string {
+	return s.buf.String()
+}
+
+func (s *Sink) Contains(str string) bool {
+	return bytes.Contains(s.buf.Bytes(), []byte(str))
+}
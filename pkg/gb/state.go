@@ -0,0 +1,157 @@
+package gb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/state"
+)
+
+/*
+Save state format, all values are little endian
+
+	header:
+	 magic "GBST", version(uint16), title(16 bytes), cycle(uint32)
+	section * N:
+	 tag(4 bytes), size(uint32), payload(size bytes)
+*/
+const stateMagic = "GBST"
+
+// StateVersion is incremented when the format is changed
+const StateVersion uint16 = 9
+
+// maxSectionSize bounds size of a section read from a state file
+// The largest section is CART with 128KB RAM, this leaves room for the format to grow
+const maxSectionSize = 1 << 20
+
+var ErrInvalidState = errors.New("invalid save state")
+
+type section struct {
+	tag       string
+	component state.Component
+}
+
+func (gb *GB) sections() []section {
+	return []section{
+		{"CPU ", gb.cpu},
+		{"IRQ ", gb.irq},
+		{"TIMR", gb.timer},
+		{"SERI", gb.serial},
+		{"PAD ", gb.pad},
+		{"APU ", gb.apu},
+		{"GPU ", gb.gpu},
+		{"BUS ", gb.bus},
+		{"CART", gb.Cartridge},
+	}
+}
+
+func (gb *GB) title() [16]byte {
+	var title [16]byte
+	copy(title[:], gb.Cartridge.Title)
+	return title
+}
+
+// SaveState writes snapshot of the whole machine
+func (gb *GB) SaveState(w io.Writer) error {
+	sw := state.NewWriter(w)
+	sw.Write([]byte(stateMagic))
+	sw.Write(StateVersion)
+	sw.Write(gb.title())
+	sw.Write(uint32(gb.currentCycle))
+
+	for _, s := range gb.sections() {
+		var buf bytes.Buffer
+		cw := state.NewWriter(&buf)
+		s.component.SaveState(cw)
+		if err := cw.Err(); err != nil {
+			return fmt.Errorf("save %s: %w", s.tag, err)
+		}
+
+		sw.Write([]byte(s.tag))
+		sw.Write(uint32(buf.Len()))
+		sw.Write(buf.Bytes())
+	}
+
+	return sw.Err()
+}
+
+// LoadState restores snapshot written by SaveState of the same game
+// All sections are checked before restoring, but the machine can be
+// left partially restored when a section is broken
+func (gb *GB) LoadState(r io.Reader) error {
+	sr := state.NewReader(r)
+
+	var (
+		magic   [4]byte
+		version uint16
+		title   [16]byte
+		cycle   uint32
+	)
+	sr.Read(&magic)
+	sr.Read(&version)
+	sr.Read(&title)
+	sr.Read(&cycle)
+	if err := sr.Err(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidState, err)
+	}
+
+	if string(magic[:]) != stateMagic {
+		return fmt.Errorf("%w: unknown magic %q", ErrInvalidState, magic)
+	}
+	if version != StateVersion {
+		return fmt.Errorf("%w: version %d is not supported", ErrInvalidState, version)
+	}
+	if title != gb.title() {
+		return fmt.Errorf("%w: state is for %q", ErrInvalidState, bytes.TrimRight(title[:], "\x00"))
+	}
+
+	payloads := map[string][]byte{}
+	for {
+		var (
+			tag  [4]byte
+			size uint32
+		)
+		sr.Read(&tag)
+		if sr.Err() == io.EOF {
+			break
+		}
+		sr.Read(&size)
+		if err := sr.Err(); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidState, err)
+		}
+		// size is not trusted, it is checked before allocation
+		if size > maxSectionSize {
+			return fmt.Errorf("%w: section %q is too large, %d bytes", ErrInvalidState, tag, size)
+		}
+		payload := make([]byte, size)
+		sr.Read(payload)
+		if err := sr.Err(); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidState, err)
+		}
+		payloads[string(tag[:])] = payload
+	}
+
+	sections := gb.sections()
+	for _, s := range sections {
+		if _, ok := payloads[s.tag]; !ok {
+			return fmt.Errorf("%w: missing section %q", ErrInvalidState, s.tag)
+		}
+	}
+
+	for _, s := range sections {
+		br := bytes.NewReader(payloads[s.tag])
+		cr := state.NewReader(br)
+		s.component.LoadState(cr)
+		if err := cr.Err(); err != nil {
+			return fmt.Errorf("%w: load %q: %v", ErrInvalidState, s.tag, err)
+		}
+		if br.Len() != 0 {
+			return fmt.Errorf("%w: section %q has %d extra bytes", ErrInvalidState, s.tag, br.Len())
+		}
+	}
+	gb.currentCycle = uint(cycle)
+
+	return nil
+}
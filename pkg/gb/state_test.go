@@ -0,0 +1,63 @@
+package gb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGB_State(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		gb := setupFile(t, "helloworld/hello.gb")
+		for i := 0; i < 30; i++ {
+			gb.Step()
+		}
+
+		var buf bytes.Buffer
+		assert.NoError(t, gb.SaveState(&buf))
+		saved := buf.Bytes()
+
+		for i := 0; i < 30; i++ {
+			gb.Step()
+		}
+		want, _ := gb.Display()
+
+		assert.NoError(t, gb.LoadState(bytes.NewReader(saved)))
+		for i := 0; i < 30; i++ {
+			gb.Step()
+		}
+		got, _ := gb.Display()
+		assert.Equal(t, want.Pix, got.Pix)
+
+		// saving again from restored state gives the same bytes
+		assert.NoError(t, gb.LoadState(bytes.NewReader(saved)))
+		buf.Reset()
+		assert.NoError(t, gb.SaveState(&buf))
+		assert.Equal(t, saved, buf.Bytes())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		gb := setupFile(t, "helloworld/hello.gb")
+		var buf bytes.Buffer
+		assert.NoError(t, gb.SaveState(&buf))
+		saved := buf.Bytes()
+
+		broken := append([]byte("XXXX"), saved[4:]...)
+		assert.ErrorIs(t, gb.LoadState(bytes.NewReader(broken)), ErrInvalidState)
+
+		truncated := saved[:len(saved)-1]
+		assert.ErrorIs(t, gb.LoadState(bytes.NewReader(truncated)), ErrInvalidState)
+
+		// header is 26 bytes, size of the first section is broken
+		huge := append([]byte{}, saved...)
+		binary.LittleEndian.PutUint32(huge[30:], 0xFFFFFFFF)
+		err := gb.LoadState(bytes.NewReader(huge))
+		assert.ErrorIs(t, err, ErrInvalidState)
+		assert.Contains(t, err.Error(), "too large")
+
+		other := setupFile(t, "blargg/cpu_instrs/cpu_instrs.gb")
+		assert.ErrorIs(t, other.LoadState(bytes.NewReader(saved)), ErrInvalidState)
+	})
+}
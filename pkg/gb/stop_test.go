@@ -0,0 +1,126 @@
+package gb
+
+import (
+	"testing"
+
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/cpu"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/interrupt"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/pad"
+	"github.com/stretchr/testify/assert"
+)
+
+// STOP, INC A, INC A, JR -2
+// A is 1 when STOP is 2 bytes, and 2 when 1 byte
+var stopProgram = []byte{0x10, 0x3C, 0x3C, 0x18, 0xFE}
+
+func setupSTOP(flag byte) *GB {
+	gb := setupInterrupt(flag, stopProgram...)
+	// select buttons
+	gb.bus.WriteByte(0xFF00, 0x10)
+	return gb
+}
+
+func TestGB_STOP(t *testing.T) {
+	assertBlank := func(t *testing.T, gb *GB) {
+		t.Helper()
+		screen, _ := gb.Display()
+		c := screen.RGBAAt(0, 0)
+		for y := 0; y < screen.Rect.Dy(); y++ {
+			for x := 0; x < screen.Rect.Dx(); x++ {
+				if !assert.Equal(t, c, screen.RGBAAt(x, y)) {
+					return
+				}
+			}
+		}
+	}
+
+	tests := []struct {
+		name string
+		// interrupt pending
+		flag byte
+		want byte
+	}{
+		{"2 bytes", 0, 1},
+		{"1 byte with interrupt pending", interrupt.SerialFlag, 2},
+	}
+	for _, tt := range tests {
+		t.Run("low power mode "+tt.name, func(t *testing.T) {
+			gb := setupSTOP(tt.flag)
+			gb.Step()
+			assert.True(t, gb.cpu.Stop)
+			assert.Equal(t, byte(0), gb.ReadByte(0xFF04), "DIV is reset and stopped")
+			assert.Equal(t, byte(0), gb.cpu.Reg.R[cpu.A])
+			ly := gb.ReadByte(0xFF44)
+			assertBlank(t, gb)
+
+			gb.Step()
+			assert.True(t, gb.cpu.Stop)
+			assert.Equal(t, ly, gb.ReadByte(0xFF44), "PPU is stopped")
+
+			gb.Press(pad.A)
+			gb.Step()
+			assert.False(t, gb.cpu.Stop)
+			assert.Equal(t, tt.want, gb.cpu.Reg.R[cpu.A])
+			assert.NotEqual(t, byte(0), gb.ReadByte(0xFF04))
+		})
+	}
+
+	t.Run("not woken up by buttons not selected", func(t *testing.T) {
+		gb := setupSTOP(0)
+		// select directions
+		gb.bus.WriteByte(0xFF00, 0x20)
+		gb.Step()
+		gb.Press(pad.A)
+		gb.Step()
+		assert.True(t, gb.cpu.Stop)
+
+		gb.Press(pad.Up)
+		gb.Step()
+		assert.False(t, gb.cpu.Stop)
+	})
+
+	t.Run("button held enters HALT", func(t *testing.T) {
+		gb := setupSTOP(0)
+		gb.Press(pad.A)
+		gb.Step()
+		assert.False(t, gb.cpu.Stop)
+		assert.True(t, gb.cpu.Halt)
+		assert.NotEqual(t, byte(0), gb.ReadByte(0xFF04), "DIV is not reset")
+
+		// HALT is exited by interrupt
+		gb.irq.IE = interrupt.VBlankFlag
+		gb.Step()
+		assert.False(t, gb.cpu.Halt)
+		assert.Equal(t, byte(1), gb.cpu.Reg.R[cpu.A])
+	})
+
+	t.Run("button held with interrupt pending", func(t *testing.T) {
+		gb := setupSTOP(interrupt.SerialFlag)
+		gb.Press(pad.A)
+		gb.Step()
+		assert.False(t, gb.cpu.Stop)
+		assert.False(t, gb.cpu.Halt)
+		assert.Equal(t, byte(2), gb.cpu.Reg.R[cpu.A])
+	})
+
+	t.Run("speed switch", func(t *testing.T) {
+		gb := setupROM(0x80, stopProgram...)
+		gb.cpu.Reg.R[cpu.A] = 0
+		gb.bus.WriteByte(0xFF4D, 0x01)
+		gb.Step()
+		assert.False(t, gb.cpu.Stop)
+		assert.True(t, gb.bus.DoubleSpeed())
+		assert.Equal(t, byte(1), gb.cpu.Reg.R[cpu.A])
+	})
+
+	t.Run("speed switch resets DIV", func(t *testing.T) {
+		gb := setupROM(0x80, stopProgram...)
+		gb.bus.WriteByte(0xFF4D, 0x01)
+		for gb.ReadByte(0xFF04) == 0 {
+			gb.timer.Tick(4)
+		}
+		gb.cpu.Step()
+		assert.True(t, gb.bus.DoubleSpeed())
+		assert.Equal(t, byte(0), gb.ReadByte(0xFF04))
+	})
+}
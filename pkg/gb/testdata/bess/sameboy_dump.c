@@ -0,0 +1,22 @@
+#include <stdio.h>
+#include <stdlib.h>
+#include "Core/gb.h"
+
+static uint32_t pixels[160 * 144];
+static uint32_t rgb_encode(GB_gameboy_t *gb, uint8_t r, uint8_t g, uint8_t b) { return r << 16 | g << 8 | b; }
+
+int main(int argc, char **argv)
+{
+    if (argc != 5) {
+        fprintf(stderr, "usage: %s boot.bin rom.gb frames out.s0\n", argv[0]);
+        return 1;
+    }
+    GB_gameboy_t gb;
+    GB_init(&gb, GB_MODEL_DMG_B);
+    if (GB_load_boot_rom(&gb, argv[1]) || GB_load_rom(&gb, argv[2])) return 1;
+    GB_set_pixels_output(&gb, pixels);
+    GB_set_rgb_encode_callback(&gb, rgb_encode);
+    int frames = atoi(argv[3]);
+    for (int i = 0; i < frames; i++) GB_run_frame(&gb);
+    return GB_save_state(&gb, argv[4]);
+}
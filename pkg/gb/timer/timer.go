@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/interrupt"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/state"
 	"github.com/Teshima-Tatsuya/GoBoy/pkg/types"
 )
 
@@ -41,7 +42,7 @@ func (t *Timer) Tick(cycle uint) {
 	for i := uint(0); i < cycle; i++ {
 		t.counter += 4
 
-		// TODO double speed for GBC
+		// cycle is of CPU, so timer runs twice as fast in double speed mode
 		if t.counter%256 == 0 {
 			t.DIV++
 		}
@@ -114,3 +115,19 @@ func (t *Timer) Write(addr types.Addr, v byte) {
 func (t *Timer) started() bool {
 	return t.TAC&0x04 == 0x04
 }
+
+func (t *Timer) SaveState(w *state.Writer) {
+	w.Write(t.counter)
+	w.Write(t.DIV)
+	w.Write(t.TIMA)
+	w.Write(t.TMA)
+	w.Write(t.TAC)
+}
+
+func (t *Timer) LoadState(r *state.Reader) {
+	r.Read(&t.counter)
+	r.Read(&t.DIV)
+	r.Read(&t.TIMA)
+	r.Read(&t.TMA)
+	r.Read(&t.TAC)
+}
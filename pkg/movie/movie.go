@@ -0,0 +1,131 @@
+package movie
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/pad"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/state"
+)
+
+/*
+Movie file format, all values are little endian
+
+	magic "GBMV", version(uint16), ROM hash(20 bytes), frames(uint32), frame hash(20 bytes)
+	start state size(uint32), start state written by gb.SaveState
+	event count(uint32), event * N:
+	 frame(uint32), button(1 byte), pressed(1 byte)
+*/
+const movieMagic = "GBMV"
+
+// Version is incremented when the format is changed
+const Version uint16 = 1
+
+var (
+	ErrInvalidMovie = errors.New("invalid movie")
+	ErrROMMismatch  = errors.New("movie is recorded with other ROM")
+	// final frame differs from the recorded one
+	ErrDesync = errors.New("movie desynced")
+)
+
+type Hash [sha1.Size]byte
+
+func (h Hash) String() string {
+	return fmt.Sprintf("%x", h[:])
+}
+
+func ROMHash(romData []byte) Hash {
+	return sha1.Sum(romData)
+}
+
+// FrameHash is hash of the current screen
+func FrameHash(g *gb.GB) Hash {
+	return g.FrameHash()
+}
+
+// Event is press or release of a button at the frame
+type Event struct {
+	Frame   uint32
+	Button  pad.Button
+	Pressed bool
+}
+
+type Movie struct {
+	ROMHash Hash
+	// number of frames recorded
+	Frames    uint32
+	FrameHash Hash
+	// state at frame 0
+	State  []byte
+	Events []Event
+}
+
+func (m *Movie) Write(w io.Writer) error {
+	sw := state.NewWriter(w)
+	sw.Write([]byte(movieMagic))
+	sw.Write(Version)
+	sw.Write(m.ROMHash)
+	sw.Write(m.Frames)
+	sw.Write(m.FrameHash)
+	sw.Write(uint32(len(m.State)))
+	sw.Write(m.State)
+	sw.Write(uint32(len(m.Events)))
+	for _, e := range m.Events {
+		sw.Write(e.Frame)
+		sw.Write(e.Button)
+		sw.Write(e.Pressed)
+	}
+	return sw.Err()
+}
+
+func Read(r io.Reader) (*Movie, error) {
+	sr := state.NewReader(r)
+	m := &Movie{}
+
+	var (
+		magic   [4]byte
+		version uint16
+		size    uint32
+	)
+	sr.Read(&magic)
+	sr.Read(&version)
+	if err := sr.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMovie, err)
+	}
+	if string(magic[:]) != movieMagic {
+		return nil, fmt.Errorf("%w: unknown magic %q", ErrInvalidMovie, magic)
+	}
+	if version != Version {
+		return nil, fmt.Errorf("%w: version %d is not supported", ErrInvalidMovie, version)
+	}
+
+	sr.Read(&m.ROMHash)
+	sr.Read(&m.Frames)
+	sr.Read(&m.FrameHash)
+	sr.Read(&size)
+	if err := sr.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMovie, err)
+	}
+	var st bytes.Buffer
+	if _, err := io.CopyN(&st, r, int64(size)); err != nil {
+		return nil, fmt.Errorf("%w: start state: %v", ErrInvalidMovie, err)
+	}
+	m.State = st.Bytes()
+
+	sr.Read(&size)
+	for i := uint32(0); i < size && sr.Err() == nil; i++ {
+		var e Event
+		sr.Read(&e.Frame)
+		sr.Read(&e.Button)
+		sr.Read(&e.Pressed)
+		m.Events = append(m.Events, e)
+	}
+	if err := sr.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMovie, err)
+	}
+	return m, nil
+}
@@ -0,0 +1,98 @@
+package movie
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/pad"
+	"github.com/Teshima-Tatsuya/GoBoy/test/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func readROM(t *testing.T, file string) []byte {
+	romData, err := ioutil.ReadFile("../../test/rom/" + file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return romData
+}
+
+func TestRecorder(t *testing.T) {
+	romData := readROM(t, "helloworld/hello.gb")
+	g := gb.NewGB(romData)
+	in := mock.NewMockInput(0, pad.A, pad.A|pad.Up, pad.Up, 0)
+	r, err := NewRecorder(g, romData, in)
+	assert.NoError(t, err)
+
+	for i := 0; i < 6; i++ {
+		g.Step()
+	}
+	m := r.Finish(g)
+
+	assert.Equal(t, uint32(6), m.Frames)
+	assert.Equal(t, ROMHash(romData), m.ROMHash)
+	assert.Equal(t, FrameHash(g), m.FrameHash)
+	assert.Equal(t, []Event{
+		{1, pad.A, true},
+		{2, pad.Up, true},
+		{3, pad.A, false},
+		{4, pad.Up, false},
+	}, m.Events)
+}
+
+func TestPlayer(t *testing.T) {
+	romData := readROM(t, "helloworld/hello.gb")
+	g := gb.NewGB(romData)
+	for i := 0; i < 10; i++ {
+		g.Step()
+	}
+
+	in := mock.NewMockInput(pad.Start, pad.Start, 0, pad.B, 0)
+	r, err := NewRecorder(g, romData, in)
+	assert.NoError(t, err)
+	for i := 0; i < 30; i++ {
+		g.Step()
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, r.Finish(g).Write(&buf))
+	saved := buf.Bytes()
+
+	t.Run("play", func(t *testing.T) {
+		m, err := Read(bytes.NewReader(saved))
+		assert.NoError(t, err)
+
+		g := gb.NewGB(romData)
+		p, err := NewPlayer(g, romData, m)
+		assert.NoError(t, err)
+
+		var got []pad.Button
+		for !p.Done() {
+			g.Step()
+			got = append(got, p.held)
+		}
+		assert.Equal(t, 30, len(got))
+		assert.Equal(t, []pad.Button{pad.Start, pad.Start, 0, pad.B, 0}, got[:5])
+		assert.NoError(t, p.Verify(g))
+
+		m.FrameHash[0] ^= 0xFF
+		assert.ErrorIs(t, p.Verify(g), ErrDesync)
+	})
+
+	t.Run("other ROM", func(t *testing.T) {
+		m, _ := Read(bytes.NewReader(saved))
+		other := readROM(t, "blargg/cpu_instrs/cpu_instrs.gb")
+		_, err := NewPlayer(gb.NewGB(other), other, m)
+		assert.ErrorIs(t, err, ErrROMMismatch)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := Read(bytes.NewReader(append([]byte("XXXX"), saved[4:]...)))
+		assert.ErrorIs(t, err, ErrInvalidMovie)
+		_, err = Read(bytes.NewReader(saved[:len(saved)-1]))
+		assert.ErrorIs(t, err, ErrInvalidMovie)
+		_, err = Read(bytes.NewReader(saved[:100]))
+		assert.ErrorIs(t, err, ErrInvalidMovie)
+	})
+}
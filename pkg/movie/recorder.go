@@ -0,0 +1,113 @@
+package movie
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb"
+	"github.com/Teshima-Tatsuya/GoBoy/pkg/gb/pad"
+)
+
+// Recorder is gb.InputProvider which records buttons from input
+type Recorder struct {
+	input gb.InputProvider
+	movie *Movie
+	frame uint32
+	held  pad.Button
+}
+
+// NewRecorder starts recording from the current state of g
+// g reads buttons from input through the recorder
+func NewRecorder(g *gb.GB, romData []byte, input gb.InputProvider) (*Recorder, error) {
+	var st bytes.Buffer
+	if err := g.SaveState(&st); err != nil {
+		return nil, err
+	}
+
+	r := &Recorder{
+		input: input,
+		movie: &Movie{
+			ROMHash: ROMHash(romData),
+			State:   st.Bytes(),
+		},
+	}
+	g.SetInputProvider(r)
+	return r, nil
+}
+
+func (r *Recorder) Buttons() pad.Button {
+	var buttons pad.Button
+	if r.input != nil {
+		buttons = r.input.Buttons()
+	}
+
+	for b := pad.A; b != 0; b <<= 1 {
+		if changed := (r.held ^ buttons) & b; changed != 0 {
+			r.movie.Events = append(r.movie.Events, Event{
+				Frame:   r.frame,
+				Button:  b,
+				Pressed: buttons&b != 0,
+			})
+		}
+	}
+	r.held = buttons
+	r.frame++
+	return buttons
+}
+
+// Finish returns the movie recorded until the current frame of g
+func (r *Recorder) Finish(g *gb.GB) *Movie {
+	r.movie.Frames = r.frame
+	r.movie.FrameHash = FrameHash(g)
+	return r.movie
+}
+
+// Player is gb.InputProvider which plays buttons in movie
+// RTC of MBC3 follows wall clock, so games reading it can desync
+type Player struct {
+	movie *Movie
+	frame uint32
+	next  int
+	held  pad.Button
+}
+
+// NewPlayer restores the start state of movie, and g reads buttons from the player
+func NewPlayer(g *gb.GB, romData []byte, m *Movie) (*Player, error) {
+	if hash := ROMHash(romData); hash != m.ROMHash {
+		return nil, fmt.Errorf("%w: %s", ErrROMMismatch, m.ROMHash)
+	}
+	if err := g.LoadState(bytes.NewReader(m.State)); err != nil {
+		return nil, err
+	}
+
+	p := &Player{movie: m}
+	g.SetInputProvider(p)
+	return p, nil
+}
+
+func (p *Player) Buttons() pad.Button {
+	events := p.movie.Events
+	for ; p.next < len(events) && events[p.next].Frame <= p.frame; p.next++ {
+		e := events[p.next]
+		if e.Pressed {
+			p.held |= e.Button
+		} else {
+			p.held &^= e.Button
+		}
+	}
+	p.frame++
+	return p.held
+}
+
+// Done returns true when all frames are played
+func (p *Player) Done() bool {
+	return p.frame >= p.movie.Frames
+}
+
+// Verify checks the current screen is the same as the recorded one
+func (p *Player) Verify(g *gb.GB) error {
+	if hash := FrameHash(g); hash != p.movie.FrameHash {
+		return fmt.Errorf("%w: frame hash is %s, want %s", ErrDesync, hash, p.movie.FrameHash)
+	}
+	return nil
+}
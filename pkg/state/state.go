@@ -0,0 +1,62 @@
+package state
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// Component is a part of GB which can save and restore its state
+type Component interface {
+	SaveState(w *Writer)
+	LoadState(r *Reader)
+}
+
+// Writer writes values in little endian
+// The first error is kept, and following writes are skipped
+type Writer struct {
+	w   io.Writer
+	err error
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		w: w,
+	}
+}
+
+// v must be a fixed size value, or slice/array of them
+func (w *Writer) Write(v interface{}) {
+	if w.err != nil {
+		return
+	}
+	w.err = binary.Write(w.w, binary.LittleEndian, v)
+}
+
+func (w *Writer) Err() error {
+	return w.err
+}
+
+// Reader reads values written by Writer
+// The first error is kept, and following reads are skipped
+type Reader struct {
+	r   io.Reader
+	err error
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{
+		r: r,
+	}
+}
+
+// v must be a pointer to fixed size value, or slice of them
+func (r *Reader) Read(v interface{}) {
+	if r.err != nil {
+		return
+	}
+	r.err = binary.Read(r.r, binary.LittleEndian, v)
+}
+
+func (r *Reader) Err() error {
+	return r.err
+}
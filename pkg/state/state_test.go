@@ -0,0 +1,44 @@
+package state
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestState_ReadWrite(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Write(byte(0x12))
+	w.Write(uint16(0x3456))
+	w.Write(true)
+	w.Write([]byte{0x01, 0x02, 0x03})
+	assert.NoError(t, w.Err())
+	assert.Equal(t, []byte{0x12, 0x56, 0x34, 0x01, 0x01, 0x02, 0x03}, buf.Bytes())
+
+	var (
+		b  byte
+		u  uint16
+		ok bool
+		s  = make([]byte, 3)
+	)
+	r := NewReader(&buf)
+	r.Read(&b)
+	r.Read(&u)
+	r.Read(&ok)
+	r.Read(s)
+	assert.NoError(t, r.Err())
+	assert.Equal(t, byte(0x12), b)
+	assert.Equal(t, uint16(0x3456), u)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, []byte{0x01, 0x02, 0x03}, s)
+
+	t.Run("error is kept", func(t *testing.T) {
+		r.Read(&b)
+		assert.Equal(t, io.EOF, r.Err())
+		r.Read(&u)
+		assert.Equal(t, io.EOF, r.Err())
+	})
+}
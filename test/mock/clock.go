@@ -0,0 +1,20 @@
+package mock
+
+import "time"
+
+// MockClock is a clock which advances only by Advance
+type MockClock struct {
+	t time.Time
+}
+
+func NewMockClock(now time.Time) *MockClock {
+	return &MockClock{t: now}
+}
+
+func (c *MockClock) Now() time.Time {
+	return c.t
+}
+
+func (c *MockClock) Advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
@@ -0,0 +1,23 @@
+package mock
+
+import "github.com/Teshima-Tatsuya/GoBoy/pkg/gb/pad"
+
+// MockInput returns buttons in order a frame each, and keeps the last one after all
+type MockInput struct {
+	buttons []pad.Button
+}
+
+func NewMockInput(buttons ...pad.Button) *MockInput {
+	return &MockInput{buttons: buttons}
+}
+
+func (in *MockInput) Buttons() pad.Button {
+	if len(in.buttons) == 0 {
+		return 0
+	}
+	b := in.buttons[0]
+	if len(in.buttons) > 1 {
+		in.buttons = in.buttons[1:]
+	}
+	return b
+}
@@ -0,0 +1,29 @@
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include "Core/gb.h"
+
+/* writes luminance of each pixel, with grey palette and no color correction */
+static uint32_t pixels[160 * 144];
+static uint32_t rgb_encode(GB_gameboy_t *gb, uint8_t r, uint8_t g, uint8_t b) { return r * 299 + g * 587 + b * 114; }
+
+int main(int argc, char **argv)
+{
+    if (argc != 6) {
+        fprintf(stderr, "usage: %s dmg|cgb boot.bin rom.gb frames out.raw\n", argv[0]);
+        return 1;
+    }
+    GB_gameboy_t gb;
+    GB_init(&gb, strcmp(argv[1], "cgb") == 0 ? GB_MODEL_CGB_E : GB_MODEL_DMG_B);
+    if (GB_load_boot_rom(&gb, argv[2]) || GB_load_rom(&gb, argv[3])) return 1;
+    GB_set_pixels_output(&gb, pixels);
+    GB_set_rgb_encode_callback(&gb, rgb_encode);
+    GB_set_color_correction_mode(&gb, GB_COLOR_CORRECTION_DISABLED);
+    GB_set_palette(&gb, &GB_PALETTE_GREY);
+    int frames = atoi(argv[4]);
+    for (int i = 0; i < frames; i++) GB_run_frame(&gb);
+    FILE *f = fopen(argv[5], "wb");
+    fwrite(pixels, sizeof(pixels), 1, f);
+    fclose(f);
+    return 0;
+}
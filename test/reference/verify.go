@@ -0,0 +1,224 @@
+//go:build ignore
+// +build ignore
+
+// verify compares golden images in test/expected with screens of SameBoy
+// Run from the repository root
+//
+//	go run ./test/reference/verify.go -shot ./shot
+package main
+
+import (
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+var (
+	shot  = flag.String("shot", "./shot", "shot binary built from test/reference/shot.c")
+	extra = flag.Int("extra", 200, "frames run more than the test, so that the screen of SameBoy settles")
+	out   = flag.String("out", "test/diff/reference", "directory to write screens which differ")
+)
+
+// entry matches {"name", "file", frame of the tables in pkg/gb/gb_test.go
+var entry = regexp.MustCompile(`\{"([^"]+)", "([^"]+)", (\d+)`)
+
+// dmgBoot sets registers as GoBoy does after boot, and unmaps itself at 0x00FE
+var dmgBoot = bootROM(0x100, []byte{
+	0x31, 0xFE, 0xFF, // LD SP,$FFFE
+	0x3E, 0xFC, 0xE0, 0x47, // LD A,$FC / LDH (BGP),A
+	0x21, 0x00, 0x01, 0xE5, 0xF1, // LD HL,$0100 / PUSH HL / POP AF
+	0x01, 0x13, 0xFF, // LD BC,$FF13
+	0x11, 0xC1, 0x00, // LD DE,$00C1
+	0x21, 0x03, 0x84, // LD HL,$8403
+	0xF5, 0x3E, 0x91, 0xE0, 0x40, 0xF1, // PUSH AF / LD A,$91 / LDH (LCDC),A / POP AF
+	0xC3, 0xFC, 0x00, // JP $00FC
+})
+
+// cgbBoot also keeps CGB mode by KEY0
+var cgbBoot = bootROM(0x900, []byte{
+	0x31, 0xFE, 0xFF, // LD SP,$FFFE
+	0x3E, 0xFC, 0xE0, 0x47, // LD A,$FC / LDH (BGP),A
+	0x3E, 0x80, 0xE0, 0x4C, // LD A,$80 / LDH (KEY0),A
+	0x21, 0x80, 0x11, 0xE5, 0xF1, // LD HL,$1180 / PUSH HL / POP AF
+	0x01, 0x00, 0x00, // LD BC,$0000
+	0x11, 0x56, 0xFF, // LD DE,$FF56
+	0x21, 0x0D, 0x00, // LD HL,$000D
+	0xF5, 0x3E, 0x91, 0xE0, 0x40, 0xF1, // PUSH AF / LD A,$91 / LDH (LCDC),A / POP AF
+	0xC3, 0xFC, 0x00, // JP $00FC
+})
+
+func bootROM(size int, program []byte) []byte {
+	rom := make([]byte, size)
+	copy(rom, program)
+	// LDH ($50),A unmaps boot ROM, and PC reaches 0x0100
+	copy(rom[0xFE:], []byte{0xE0, 0x50})
+	return rom
+}
+
+// shades maps luminance to the rank of shade, 0 is the lightest
+func shades(lum []int) []int {
+	set := map[int]bool{}
+	for _, v := range lum {
+		set[v] = true
+	}
+	keys := []int{}
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(keys)))
+	rank := map[int]int{}
+	for i, k := range keys {
+		rank[k] = i
+	}
+	ranks := make([]int, len(lum))
+	for i, v := range lum {
+		ranks[i] = rank[v]
+	}
+	return ranks
+}
+
+func readPNG(path string) ([]int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, err := png.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+	rect := img.Bounds()
+	lum := []int{}
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			lum = append(lum, int(r>>8)*299+int(g>>8)*587+int(b>>8)*114)
+		}
+	}
+	return lum, nil
+}
+
+// sameboy returns luminance of each pixel after running ROM for frames
+func sameboy(rom string, frames int) ([]int, error) {
+	data, err := ioutil.ReadFile(rom)
+	if err != nil {
+		return nil, err
+	}
+	model, boot := "dmg", dmgBoot
+	if data[0x0143]&0x80 != 0 {
+		model, boot = "cgb", cgbBoot
+	}
+
+	dir, err := ioutil.TempDir("", "verify")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	bootPath, rawPath := filepath.Join(dir, "boot.bin"), filepath.Join(dir, "screen.raw")
+	if err := ioutil.WriteFile(bootPath, boot, 0666); err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(*shot, model, bootPath, rom, fmt.Sprint(frames), rawPath)
+	if b, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("%v: %s", err, b)
+	}
+
+	raw, err := ioutil.ReadFile(rawPath)
+	if err != nil {
+		return nil, err
+	}
+	lum := make([]int, 160*144)
+	for i := range lum {
+		lum[i] = int(binary.LittleEndian.Uint32(raw[i*4:]))
+	}
+	return lum, nil
+}
+
+func writePNG(path string, ranks []int) error {
+	img := image.NewGray(image.Rect(0, 0, 160, 144))
+	for i, v := range ranks {
+		img.Pix[i] = uint8(0xFF - v*0x55)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func verify(name, file string, frames int) (string, error) {
+	golden, err := readPNG(filepath.Join("test/expected", name, file+".png"))
+	if os.IsNotExist(err) {
+		return "no golden", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	lum, err := sameboy(filepath.Join("test/rom", name, file+".gb"), frames+*extra)
+	if err != nil {
+		return "", err
+	}
+
+	expected, actual := shades(lum), shades(golden)
+	count := 0
+	for i := range actual {
+		if actual[i] != expected[i] {
+			count++
+		}
+	}
+	if count == 0 {
+		return "match", nil
+	}
+
+	key := filepath.Join(*out, name, file)
+	if err := os.MkdirAll(filepath.Dir(key), 0777); err != nil {
+		return "", err
+	}
+	if err := writePNG(key+"_sameboy.png", expected); err != nil {
+		return "", err
+	}
+	if err := writePNG(key+"_golden.png", actual); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d pixels differ", count), nil
+}
+
+func main() {
+	flag.Parse()
+	src, err := ioutil.ReadFile("pkg/gb/gb_test.go")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	failed := false
+	for _, m := range entry.FindAllStringSubmatch(string(src), -1) {
+		var frames int
+		fmt.Sscan(m[3], &frames)
+		result, err := verify(m[1], m[2], frames)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if result != "match" && result != "no golden" {
+			failed = true
+		}
+		fmt.Printf("%-60s %s\n", m[1]+"/"+m[2], result)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}